@@ -0,0 +1,91 @@
+package ctxerrpool
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// awaiter is a channel returned by Await that closes once the Pool has completed a number of work items.
+type awaiter struct {
+	done   chan struct{}
+	once   sync.Once
+	stop   func() bool
+	target uint64
+}
+
+// close closes the channel if it has not been closed already.
+func (a *awaiter) close() {
+	a.once.Do(func() {
+		close(a.done)
+	})
+}
+
+// Await returns a channel that closes once n more work items have finished, counting from the call, or once the Pool
+// dies. Work items finish whether they succeed, fail, or are dropped, as counted by CompletedTotal. It is finer
+// grained than Wait, which waits for all of them, so a caller can report progress every n work items and carry on. If
+// n is not positive, the channel is already closed.
+//
+// If fewer than n more work items ever finish, the channel only closes when the Pool dies, so give the Pool at least n
+// more work items or select on something else as well.
+func (g *Pool) Await(n int) <-chan struct{} {
+	a := &awaiter{
+		done: make(chan struct{}),
+	}
+	if n <= 0 {
+		a.close()
+		return a.done
+	}
+
+	// Register the awaiter, so finishing work items check it.
+	g.awaitMux.Lock()
+	a.target = atomic.LoadUint64(&g.cseq) + uint64(n)
+	a.stop = context.AfterFunc(g.ctx, func() {
+		g.unawait(a)
+	})
+	g.awaiters = append(g.awaiters, a)
+	atomic.AddInt64(&g.awaiting, 1)
+	g.awaitMux.Unlock()
+
+	return a.done
+}
+
+// progress closes the channels of the awaiters whose number of work items has finished. It is called every time a
+// work item finishes and does nothing if no one is waiting.
+func (g *Pool) progress() {
+	if atomic.LoadInt64(&g.awaiting) == 0 {
+		return
+	}
+
+	g.awaitMux.Lock()
+	defer g.awaitMux.Unlock()
+	completed := atomic.LoadUint64(&g.cseq)
+	waiting := g.awaiters[:0]
+	for _, a := range g.awaiters {
+		if completed < a.target {
+			waiting = append(waiting, a)
+			continue
+		}
+		a.stop()
+		a.close()
+		atomic.AddInt64(&g.awaiting, -1)
+	}
+	clear(g.awaiters[len(waiting):])
+	g.awaiters = waiting
+}
+
+// unawait closes the channel of the awaiter and forgets it. It is called when the Pool dies before the awaiter's number
+// of work items has finished.
+func (g *Pool) unawait(a *awaiter) {
+	g.awaitMux.Lock()
+	defer g.awaitMux.Unlock()
+	for i, other := range g.awaiters {
+		if other == a {
+			g.awaiters = slices.Delete(g.awaiters, i, i+1)
+			atomic.AddInt64(&g.awaiting, -1)
+			break
+		}
+	}
+	a.close()
+}
@@ -0,0 +1,72 @@
+package ctxerrpool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ProgressFunc is called by a Batch every time one of its work items finishes. It receives the Batch's counts at the
+// time the work item finished.
+type ProgressFunc func(completed, failed, remaining int64)
+
+// Batch tracks the progress of a finite number of work items given to a Pool. Every work item added through the Batch
+// is counted as completed once it finishes, whether it succeeded, returned an error, was rejected, or was dropped
+// because the Pool died, so the counts always add up to the total.
+type Batch struct {
+	completed  int64
+	failed     int64
+	onProgress ProgressFunc
+	pool       *Pool
+	total      int64
+}
+
+// NewBatch creates a Batch for the given total number of work items. The onProgress function is optional and is
+// called every time one of the Batch's work items finishes. It may be called concurrently.
+func (g *Pool) NewBatch(total int64, onProgress ProgressFunc) *Batch {
+	return &Batch{
+		onProgress: onProgress,
+		pool:       g,
+		total:      total,
+	}
+}
+
+// AddWorkItem adds a work item to the Batch's Pool and tracks it. It behaves like Pool.AddWorkItem.
+func (b *Batch) AddWorkItem(ctx context.Context, work Work, data interface{}) WorkID {
+	return b.pool.addWorkItem(ctx, &workItem{
+		onFinish: b.finish,
+		work:     work,
+		data:     data,
+	})
+}
+
+// Completed is the number of the Batch's work items that have finished, including those that failed.
+func (b *Batch) Completed() int64 {
+	return atomic.LoadInt64(&b.completed)
+}
+
+// Failed is the number of the Batch's work items that finished with an error.
+func (b *Batch) Failed() int64 {
+	return atomic.LoadInt64(&b.failed)
+}
+
+// Remaining is the number of the Batch's work items that have not finished yet.
+func (b *Batch) Remaining() int64 {
+	return b.total - b.Completed()
+}
+
+// Total is the total number of work items the Batch was created for.
+func (b *Batch) Total() int64 {
+	return b.total
+}
+
+// finish counts a finished work item and reports the progress.
+func (b *Batch) finish(err error) {
+	failed := atomic.LoadInt64(&b.failed)
+	if err != nil {
+		failed = atomic.AddInt64(&b.failed, 1)
+	}
+	completed := atomic.AddInt64(&b.completed, 1)
+	if b.onProgress != nil {
+		b.onProgress(completed, failed, b.total-completed)
+	}
+}
@@ -0,0 +1,61 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestBatch confirms that a Batch counts every work item as completed, including rejected and dropped work items.
+func TestBatch(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+
+	// Create a batch that reports its progress on a channel.
+	progress := make(chan int64, 4)
+	batch := pool.NewBatch(4, func(completed, failed, remaining int64) {
+		progress <- completed
+	})
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Add a work item that succeeds and one that fails.
+	batch.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, "success")
+	batch.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		return io.EOF
+	}, "failure")
+
+	// Add a work item whose context is expired on arrival.
+	expiredCtx, expiredCancel := context.WithCancel(context.Background())
+	expiredCancel()
+	batch.AddWorkItem(expiredCtx, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, "expired")
+
+	// Add a work item after the pool dies.
+	pool.Wait()
+	pool.Kill()
+	batch.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, "dead")
+
+	// Confirm the progress function was called for every work item.
+	for i := 0; i < 4; i++ {
+		<-progress
+	}
+
+	// Confirm the counts.
+	if batch.Completed() != 4 || batch.Failed() != 3 || batch.Remaining() != 0 {
+		t.Errorf("Unexpected batch counts. Completed: %d. Failed: %d. Remaining: %d.", batch.Completed(),
+			batch.Failed(), batch.Remaining())
+		t.FailNow()
+	}
+}
@@ -0,0 +1,130 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"ctxerrpool"
+)
+
+// benchmarkWorkers are the numbers of workers the benchmarks run with.
+var benchmarkWorkers = []uint{1, 4, 16}
+
+// benchmarkQueueSizes are the queue sizes the benchmarks run with.
+var benchmarkQueueSizes = []uint{0, 64}
+
+// BenchmarkDispatchOrder measures a recursive workload, where each work item adds 2 more until a depth is reached,
+// with each dispatch order. The most work items that were queued at once is reported as max-queued, which shows how
+// LIFO keeps the queue, and the memory it holds, small.
+func BenchmarkDispatchOrder(b *testing.B) {
+	const depth = 12
+	orders := []struct {
+		name  string
+		order ctxerrpool.DispatchOrder
+	}{{"FIFO", ctxerrpool.FIFO}, {"LIFO", ctxerrpool.LIFO}}
+	for _, order := range orders {
+		b.Run(order.name, func(b *testing.B) {
+
+			// Create a worker pool.
+			pool := ctxerrpool.New(4, func(pool *ctxerrpool.Pool, err error) {
+				b.Errorf("An error occurred. Error: %v", err)
+			}, ctxerrpool.WithDispatchOrder(order.order))
+			defer pool.Kill()
+
+			// Create work that adds 2 more work items until the depth is reached and keeps track of the queue.
+			var maxQueued int64
+			var work ctxerrpool.Work
+			work = func(workCtx context.Context, data interface{}) error {
+				if queued := int64(pool.Pending()); queued > atomic.LoadInt64(&maxQueued) {
+					atomic.StoreInt64(&maxQueued, queued)
+				}
+				if level := data.(int); level < depth {
+					pool.AddWorkItem(context.WithoutCancel(workCtx), work, level+1)
+					pool.AddWorkItem(context.WithoutCancel(workCtx), work, level+1)
+				}
+				return nil
+			}
+
+			// Give the pool the root of the tree and wait for all of it.
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				pool.AddWorkItem(context.Background(), work, 0)
+				pool.Wait()
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&maxQueued)), "max-queued")
+		})
+	}
+}
+
+// BenchmarkNoOp measures the overhead of the Pool itself with work items that do nothing.
+func BenchmarkNoOp(b *testing.B) {
+	benchmarkPool(b, func(workCtx context.Context, data interface{}) error {
+		return nil
+	})
+}
+
+// BenchmarkSpin measures throughput with work items that keep the CPU busy for a short while.
+func BenchmarkSpin(b *testing.B) {
+	benchmarkPool(b, func(workCtx context.Context, data interface{}) error {
+		sum := 0
+		for i := 0; i < 1000; i++ {
+			sum += i
+		}
+		if sum < 0 {
+			return fmt.Errorf("impossible sum %d", sum)
+		}
+		return nil
+	})
+}
+
+// BenchmarkString measures summarizing a Pool with String while its workers churn through work items.
+func BenchmarkString(b *testing.B) {
+
+	// Create a worker pool that is kept busy in the background.
+	pool := ctxerrpool.New(4, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(64))
+	defer pool.Kill()
+	go func() {
+		for !pool.Dead() {
+			pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+				return nil
+			}, nil)
+		}
+	}()
+
+	// Summarize the pool.
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = pool.String()
+	}
+}
+
+// benchmarkPool gives b.N copies of the work to Pools of every benchmarked number of workers and queue size.
+func benchmarkPool(b *testing.B, work ctxerrpool.Work) {
+	for _, workers := range benchmarkWorkers {
+		for _, size := range benchmarkQueueSizes {
+			b.Run(fmt.Sprintf("workers=%d/queue=%d", workers, size), func(b *testing.B) {
+
+				// Create a worker pool.
+				pool := ctxerrpool.New(workers, func(pool *ctxerrpool.Pool, err error) {
+					b.Errorf("An error occurred. Error: %v", err)
+				}, ctxerrpool.WithQueueSize(size))
+				defer pool.Kill()
+
+				// Create a context.
+				ctx := context.Background()
+
+				// Give the pool the work and wait for all of it.
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					pool.AddWorkItem(ctx, work, nil)
+				}
+				pool.Wait()
+			})
+		}
+	}
+}
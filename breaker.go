@@ -0,0 +1,140 @@
+package ctxerrpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BreakerState is the state of a Pool's circuit breaker.
+type BreakerState int32
+
+const (
+
+	// BreakerClosed means work items run as usual.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen means work items fail with ErrCircuitOpen without running.
+	BreakerOpen
+
+	// BreakerHalfOpen means one work item is running as a probe. Other work items fail with ErrCircuitOpen until the
+	// probe finishes.
+	BreakerHalfOpen
+)
+
+// String implements the fmt.Stringer interface.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// breaker is a circuit breaker for the work items of a Pool. A nil breaker lets everything through. The state is read
+// atomically so work items only take the lock when they fail or the state changes.
+type breaker struct {
+	clock     Clock
+	cooldown  time.Duration
+	failures  uint
+	mux       sync.Mutex
+	onChange  func(state BreakerState)
+	opened    time.Time
+	start     time.Time
+	state     int32
+	threshold uint
+	window    time.Duration
+}
+
+// newBreaker creates the circuit breaker for a Pool. If the configuration has no circuit breaker, nil is returned.
+func newBreaker(cfg config) *breaker {
+	if cfg.breakerThreshold == 0 {
+		return nil
+	}
+	return &breaker{
+		clock:     cfg.clock,
+		cooldown:  cfg.breakerCooldown,
+		onChange:  cfg.onBreakerChange,
+		threshold: cfg.breakerThreshold,
+		window:    cfg.breakerWindow,
+	}
+}
+
+// allow determines if a work item may run. If the work item is the probe of a half-open breaker, probe is true and
+// the result of the work item must be given to record.
+func (b *breaker) allow() (ok, probe bool) {
+	if b == nil || BreakerState(atomic.LoadInt32(&b.state)) == BreakerClosed {
+		return true, false
+	}
+
+	// Let one work item through as a probe once the cooldown is over.
+	b.mux.Lock()
+	switch BreakerState(b.state) {
+	case BreakerClosed:
+		b.mux.Unlock()
+		return true, false
+	case BreakerOpen:
+		if b.clock.Now().Sub(b.opened) >= b.cooldown {
+			b.set(BreakerHalfOpen)
+			return true, true
+		}
+	}
+	b.mux.Unlock()
+
+	return false, false
+}
+
+// record counts the result of a work item that was allowed to run. Only the result of the probe changes the state of
+// a half-open breaker.
+func (b *breaker) record(failed, probe bool) {
+	if b == nil || !failed && !probe {
+		return
+	}
+	b.mux.Lock()
+	now := b.clock.Now()
+	switch BreakerState(b.state) {
+
+	// Trip the breaker if there are too many failures in the window.
+	case BreakerClosed:
+		if b.failures == 0 || now.Sub(b.start) > b.window {
+			b.failures = 0
+			b.start = now
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.opened = now
+			b.set(BreakerOpen)
+			return
+		}
+
+	// Reset or trip the breaker based on the probe.
+	case BreakerHalfOpen:
+		if !probe {
+			break
+		}
+		b.failures = 0
+		if failed {
+			b.opened = now
+			b.set(BreakerOpen)
+			return
+		}
+		b.set(BreakerClosed)
+		return
+	}
+	b.mux.Unlock()
+}
+
+// set changes the state of the breaker, unlocks it, and then calls the onChange function, if any. The lock must be
+// held.
+func (b *breaker) set(state BreakerState) {
+	atomic.StoreInt32(&b.state, int32(state))
+	b.mux.Unlock()
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
@@ -0,0 +1,73 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// TestWithCircuitBreaker confirms that the circuit breaker opens after too many failures, fails work items fast while
+// open, and closes after a successful probe.
+func TestWithCircuitBreaker(t *testing.T) {
+
+	// Keep track of the changes to the breaker's state.
+	mux := &sync.Mutex{}
+	var states []ctxerrpool.BreakerState
+
+	// Create a worker pool with 1 worker, a circuit breaker, and a clock controlled by the test.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithClock(clock),
+		ctxerrpool.WithCircuitBreaker(3, time.Minute, time.Minute),
+		ctxerrpool.WithOnBreakerChange(func(state ctxerrpool.BreakerState) {
+			mux.Lock()
+			defer mux.Unlock()
+			states = append(states, state)
+		}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Fail enough work items to open the breaker.
+	fail := func(workCtx context.Context, data interface{}) error {
+		return errors.New("test")
+	}
+	pool.RunAll(ctx, []ctxerrpool.Work{fail, fail, fail})
+
+	// Confirm work items fail fast while the breaker is open.
+	ran := false
+	errs := pool.RunAll(ctx, []ctxerrpool.Work{func(workCtx context.Context, data interface{}) error {
+		ran = true
+		return nil
+	}})
+	if ran || !errors.Is(errs[0], ctxerrpool.ErrCircuitOpen) {
+		t.Errorf("The work item was not failed fast. Error: %v", errs[0])
+		t.FailNow()
+	}
+
+	// Let the cooldown pass and confirm a successful probe closes the breaker.
+	clock.Advance(time.Minute)
+	errs = pool.RunAll(ctx, []ctxerrpool.Work{func(workCtx context.Context, data interface{}) error {
+		return nil
+	}})
+	if errs[0] != nil {
+		t.Errorf("The probe did not run. Error: %v", errs[0])
+		t.FailNow()
+	}
+
+	// Confirm the changes to the breaker's state.
+	mux.Lock()
+	defer mux.Unlock()
+	expected := []ctxerrpool.BreakerState{ctxerrpool.BreakerOpen, ctxerrpool.BreakerHalfOpen, ctxerrpool.BreakerClosed}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("Unexpected breaker states: %v.", states)
+		t.FailNow()
+	}
+}
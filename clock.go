@@ -0,0 +1,57 @@
+package ctxerrpool
+
+import (
+	"time"
+)
+
+// Clock tells the time and creates timers for a Pool. The default Clock uses the time package. Tests can give a Pool a
+// Clock they control with WithClock so time-dependent behavior is deterministic and fast.
+type Clock interface {
+
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer creates a Timer that sends the current time on its channel after at least the duration.
+	NewTimer(d time.Duration) Timer
+
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// Timer is a timer created by a Clock.
+type Timer interface {
+
+	// C returns the channel the time is sent on when the Timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. It returns false if the Timer already fired or was stopped.
+	Stop() bool
+}
+
+// realClock is a Clock that uses the time package.
+type realClock struct{}
+
+// After implements the Clock interface.
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTimer implements the Clock interface.
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{Timer: time.NewTimer(d)}
+}
+
+// Now implements the Clock interface.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// realTimer is a Timer that uses the time package.
+type realTimer struct {
+	*time.Timer
+}
+
+// C implements the Timer interface.
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
@@ -0,0 +1,44 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// TestWithClock confirms that a Pool uses the given Clock for its timers.
+func TestWithClock(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a clock controlled by the test.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithClock(clock))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until its context is canceled.
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		return nil
+	}, "running")
+	<-started
+
+	// Give the running work an hour to finish. The hour passes when the test advances the clock.
+	go pool.KillWithPolicy(ctxerrpool.KillPolicy{CancelQueued: true, WaitInFlight: time.Hour})
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+
+	// The pool should die right away.
+	select {
+	case <-pool.Death():
+	case <-ctx.Done():
+		t.Error("The pool did not use the given clock.")
+		t.FailNow()
+	}
+}
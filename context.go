@@ -0,0 +1,52 @@
+package ctxerrpool
+
+import (
+	"context"
+	"time"
+)
+
+// poolContext is a context.Context that is canceled when its Pool dies. Its Err method returns the reason the Pool
+// died.
+type poolContext struct {
+	context.Context
+	deadline time.Time
+}
+
+// Deadline returns when the Pool will be killed because of its maximum lifetime, if it has one.
+func (c poolContext) Deadline() (deadline time.Time, ok bool) {
+	return c.deadline, !c.deadline.IsZero()
+}
+
+// Err returns nil while the Pool is alive and the reason the Pool died afterwards. The reason always matches
+// context.Canceled with errors.Is.
+func (c poolContext) Err() error {
+	if c.Context.Err() == nil {
+		return nil
+	}
+	return context.Cause(c.Context)
+}
+
+// baseContext is a context.Context that falls back to the values of the Pool's base context for keys the work item's
+// context has no value for. Only values come from the base context, never its deadline or cancellation.
+type baseContext struct {
+	context.Context
+	base context.Context
+}
+
+// Value returns the value of the work item's context for the key, or the base context's if it has none.
+func (c baseContext) Value(key any) any {
+	if value := c.Context.Value(key); value != nil {
+		return value
+	}
+	return c.base.Value(key)
+}
+
+// AsContext returns a context.Context whose Done channel closes when the Pool dies and whose Err method returns the
+// reason the Pool died, such as ErrPoolKilled. Its deadline is the Pool's deadline, if it has a maximum lifetime. This
+// hands the Pool's lifecycle to any function that takes a context.
+func (g *Pool) AsContext() context.Context {
+	return poolContext{
+		Context:  g.ctx,
+		deadline: g.deadline,
+	}
+}
@@ -0,0 +1,33 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ctxerrpool"
+)
+
+// TestAsContext confirms that the context.Context for a Pool is canceled with the reason the Pool died.
+func TestAsContext(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+
+	// The context should be alive with the pool.
+	ctx := pool.AsContext()
+	if err := ctx.Err(); err != nil {
+		t.Errorf("The context of a live pool had an error. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Kill the pool.
+	pool.Kill()
+
+	// The context should be done with the reason the pool died.
+	<-ctx.Done()
+	if err := ctx.Err(); !errors.Is(err, ctxerrpool.ErrPoolKilled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("The context of a killed pool had the wrong error. Error: %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,235 @@
+// Package ctxerrpooltest provides helpers for testing code that uses ctxerrpool.
+package ctxerrpooltest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ctxerrpool"
+)
+
+// Clock is a ctxerrpool.Clock whose time only moves when Advance is called. Give it to a Pool with ctxerrpool.WithClock
+// to make time-dependent behavior deterministic. The zero value is not usable, create one with NewClock.
+type Clock struct {
+	changed chan struct{}
+	mux     sync.Mutex
+	now     time.Time
+	timers  []*timer
+}
+
+// NewClock creates a new Clock that starts at the given time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		changed: make(chan struct{}),
+		now:     now,
+	}
+}
+
+// Advance moves the Clock forward by the duration and fires every timer that is due, in the order they are due.
+func (c *Clock) Advance(d time.Duration) {
+
+	// Move the time forward and take the timers that are due.
+	c.mux.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var due []*timer
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = pending
+	c.broadcast()
+	c.mux.Unlock()
+
+	// Fire the timers outside the lock, so they can create more timers.
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].deadline.Before(due[j].deadline)
+	})
+	for _, t := range due {
+		t.fire(now)
+	}
+}
+
+// After implements the ctxerrpool.Clock interface.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// BlockUntil blocks until at least n timers are waiting to fire. Use it to wait for a goroutine under test to create
+// its timers before calling Advance.
+func (c *Clock) BlockUntil(n int) {
+	c.mux.Lock()
+	for len(c.timers) < n {
+		changed := c.changed
+		c.mux.Unlock()
+		<-changed
+		c.mux.Lock()
+	}
+	c.mux.Unlock()
+}
+
+// NewTimer implements the ctxerrpool.Clock interface. The timer fires when Advance moves the Clock to or past its
+// deadline.
+func (c *Clock) NewTimer(d time.Duration) ctxerrpool.Timer {
+	t := &timer{
+		c: make(chan time.Time, 1),
+	}
+	c.schedule(t, d)
+	return t
+}
+
+// Now implements the ctxerrpool.Clock interface.
+func (c *Clock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+// Timers returns the number of timers waiting to fire.
+func (c *Clock) Timers() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return len(c.timers)
+}
+
+// WithDeadline works like context.WithDeadline, except the deadline is measured with the Clock.
+func (c *Clock) WithDeadline(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	return c.WithTimeout(parent, deadline.Sub(c.Now()))
+}
+
+// WithTimeout works like context.WithTimeout, except the timeout is measured with the Clock. The returned context's
+// Err method returns context.DeadlineExceeded once Advance moves the Clock past the timeout.
+func (c *Clock) WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+
+	// Create the context.
+	ctx := &deadlineCtx{
+		Context:  parent,
+		deadline: c.Now().Add(d),
+		done:     make(chan struct{}),
+	}
+
+	// End the context when the timer fires or the parent is done.
+	t := &timer{
+		fn: func() {
+			ctx.cancel(context.DeadlineExceeded)
+		},
+	}
+	c.schedule(t, d)
+	stop := context.AfterFunc(parent, func() {
+		ctx.cancel(parent.Err())
+	})
+
+	return ctx, func() {
+		t.Stop()
+		stop()
+		ctx.cancel(context.Canceled)
+	}
+}
+
+// broadcast wakes up everything waiting for the timers to change. The lock must be held.
+func (c *Clock) broadcast() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// remove stops tracking the timer. It returns false if the timer was not waiting to fire.
+func (c *Clock) remove(t *timer) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// schedule sets the timer to fire after the duration. A timer that is already due fires right away.
+func (c *Clock) schedule(t *timer, d time.Duration) {
+	c.mux.Lock()
+	t.clock = c
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		now := c.now
+		c.mux.Unlock()
+		t.fire(now)
+		return
+	}
+	c.timers = append(c.timers, t)
+	c.broadcast()
+	c.mux.Unlock()
+}
+
+// timer is a ctxerrpool.Timer created by a Clock. It either sends on its channel or calls its function when it fires.
+type timer struct {
+	c        chan time.Time
+	clock    *Clock
+	deadline time.Time
+	fn       func()
+}
+
+// C implements the ctxerrpool.Timer interface.
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop implements the ctxerrpool.Timer interface.
+func (t *timer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+// fire sends the time on the timer's channel or calls its function.
+func (t *timer) fire(now time.Time) {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+// deadlineCtx is a context.Context whose deadline is measured with a Clock.
+type deadlineCtx struct {
+	context.Context
+	deadline time.Time
+	done     chan struct{}
+	err      error
+	mux      sync.Mutex
+}
+
+// Deadline implements the context.Context interface.
+func (ctx *deadlineCtx) Deadline() (deadline time.Time, ok bool) {
+	return ctx.deadline, true
+}
+
+// Done implements the context.Context interface.
+func (ctx *deadlineCtx) Done() <-chan struct{} {
+	return ctx.done
+}
+
+// Err implements the context.Context interface.
+func (ctx *deadlineCtx) Err() error {
+	ctx.mux.Lock()
+	defer ctx.mux.Unlock()
+	return ctx.err
+}
+
+// cancel ends the context with the given error, if it has not ended already.
+func (ctx *deadlineCtx) cancel(err error) {
+	ctx.mux.Lock()
+	defer ctx.mux.Unlock()
+	if ctx.err == nil {
+		ctx.err = err
+		close(ctx.done)
+	}
+}
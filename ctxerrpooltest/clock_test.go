@@ -0,0 +1,104 @@
+package ctxerrpooltest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// TestClock confirms that timers only fire when the Clock is advanced past them.
+func TestClock(t *testing.T) {
+
+	// Create a clock and a timer.
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ctxerrpooltest.NewClock(start)
+	timer := clock.NewTimer(time.Minute)
+
+	// The timer should not fire early.
+	clock.Advance(time.Second * 59)
+	select {
+	case <-timer.C():
+		t.Error("The timer fired early.")
+		t.FailNow()
+	default:
+	}
+
+	// The timer should fire on time.
+	clock.Advance(time.Second)
+	select {
+	case now := <-timer.C():
+		if !now.Equal(start.Add(time.Minute)) {
+			t.Errorf("The timer fired with the wrong time. Time: %v", now)
+			t.FailNow()
+		}
+	default:
+		t.Error("The timer did not fire.")
+		t.FailNow()
+	}
+
+	// A stopped timer should not fire.
+	timer = clock.NewTimer(time.Minute)
+	if !timer.Stop() {
+		t.Error("The timer could not be stopped.")
+		t.FailNow()
+	}
+	clock.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Error("The stopped timer fired.")
+		t.FailNow()
+	default:
+	}
+}
+
+// TestClockBlockUntil confirms that BlockUntil waits for timers to be created.
+func TestClockBlockUntil(t *testing.T) {
+
+	// Create a timer in another goroutine.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	fired := make(chan struct{})
+	go func() {
+		<-clock.After(time.Second)
+		close(fired)
+	}()
+
+	// Fire the timer once it exists.
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-fired
+}
+
+// TestClockWithTimeout confirms that contexts created by the Clock expire when the Clock is advanced.
+func TestClockWithTimeout(t *testing.T) {
+
+	// Create a context that times out.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	ctx, cancel := clock.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Confirm derived contexts see the deadline too.
+	child, cancelChild := context.WithCancel(ctx)
+	defer cancelChild()
+
+	// Let the time run out.
+	clock.Advance(time.Second)
+	<-child.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) || !errors.Is(child.Err(), context.DeadlineExceeded) {
+		t.Errorf("The context did not time out. Error: %v", child.Err())
+		t.FailNow()
+	}
+
+	// Confirm canceling the parent cancels the context.
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = clock.WithTimeout(parent, time.Second)
+	defer cancel()
+	cancelParent()
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("The context was not canceled. Error: %v", ctx.Err())
+		t.FailNow()
+	}
+}
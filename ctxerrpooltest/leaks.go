@@ -0,0 +1,83 @@
+package ctxerrpooltest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// leakGrace is how long AssertNoLeaks waits for the Pool's goroutines to return before failing the test.
+const leakGrace = time.Second
+
+// background are the functions of the goroutines a live Pool keeps running while it has no work.
+var background = []string{
+	"ctxerrpool.(*Pool).expire",
+	"ctxerrpool.(*Pool).handleErrors",
+	"ctxerrpool.(*worker).start",
+}
+
+// AssertNoLeaks fails the test if goroutines owned by the Pool are still running after a second. Goroutines are
+// matched to the Pool by their ctxerrpool.ProfileLabel, so other Pools in the test do not interfere. For a dead Pool,
+// every goroutine must have returned. For a live Pool, only the workers and the Pool's background goroutines may
+// remain, so call it after Wait. The stacks of the leaked goroutines are included in the failure.
+//
+// A leak usually means a Work function or error handler that does not return when its context ends.
+func AssertNoLeaks(t testing.TB, pool *ctxerrpool.Pool) {
+	t.Helper()
+	deadline := time.Now().Add(leakGrace)
+	for {
+		leaked := leakedGoroutines(pool)
+		if len(leaked) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("Goroutines of the pool leaked:\n\n%s", strings.Join(leaked, "\n\n"))
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// leakedGoroutines returns the stacks of the goroutines owned by the Pool that should not be running anymore.
+func leakedGoroutines(pool *ctxerrpool.Pool) (leaked []string) {
+	dead := pool.Dead()
+	label := fmt.Sprintf("%q:%q", ctxerrpool.ProfileLabel, fmt.Sprintf("%p", pool))
+
+	// Get the goroutine profile. Goroutines with the same stack and labels are grouped in a block, which starts with
+	// their count.
+	buf := &bytes.Buffer{}
+	_ = pprof.Lookup("goroutine").WriteTo(buf, 1) // Writing to a bytes.Buffer never fails.
+	for _, block := range strings.Split(buf.String(), "\n\n") {
+		if !strings.Contains(block, "# labels: ") || !strings.Contains(block, label) {
+			continue
+		}
+		if !dead && isBackground(block) {
+			continue
+		}
+		leaked = append(leaked, block)
+	}
+
+	return leaked
+}
+
+// isBackground determines if a block of the goroutine profile is for goroutines a live Pool keeps running.
+func isBackground(block string) bool {
+	for _, line := range strings.Split(block, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "#" {
+			continue
+		}
+		function, _, _ := strings.Cut(fields[2], "+")
+		for _, name := range background {
+			if function == name {
+				return true
+			}
+		}
+	}
+	return false
+}
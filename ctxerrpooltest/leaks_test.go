@@ -0,0 +1,87 @@
+package ctxerrpooltest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// recorder is a testing.TB that records failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+// Errorf records the failure.
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// TestAssertNoLeaks confirms that a Pool whose work respects its context leaves no goroutines behind, whether it is
+// alive or dead.
+func TestAssertNoLeaks(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	}, ctxerrpool.WithMaxLifetime(time.Minute))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool some work and wait for it.
+	for i := 0; i < 4; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return nil
+		}, i)
+	}
+	pool.Wait()
+
+	// Only the workers and background goroutines should remain while the pool is alive.
+	ctxerrpooltest.AssertNoLeaks(t, pool)
+
+	// Nothing should remain once the pool is dead.
+	pool.Kill()
+	<-pool.Death()
+	ctxerrpooltest.AssertNoLeaks(t, pool)
+}
+
+// TestAssertNoLeaksLeak confirms that a Work function that ignores its context is found.
+func TestAssertNoLeaksLeak(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+
+	// Create a context that is already canceled once the work is started.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Give the pool work that ignores its context until the gate is closed.
+	gate := make(chan struct{})
+	defer close(gate)
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, nil)
+	<-started
+
+	// Kill the pool while the work is still running.
+	cancel()
+	pool.Kill()
+	<-pool.Death()
+
+	// Confirm the leak is found.
+	r := &recorder{TB: t}
+	ctxerrpooltest.AssertNoLeaks(r, pool)
+	if len(r.failures) != 1 {
+		t.Errorf("Expected 1 failure, got %d.", len(r.failures))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,46 @@
+package ctxerrpool
+
+import (
+	"math/rand"
+)
+
+// dispatcher makes the choices that are otherwise left to the Go runtime with a seeded pseudo-random number generator,
+// so the same seed makes the same choices. It is not safe for concurrent use. A nil dispatcher leaves every choice to
+// the Go runtime.
+type dispatcher struct {
+	rand *rand.Rand
+}
+
+// newDispatcher creates a dispatcher for the given seed. If deterministic dispatch is off, nil is returned.
+func newDispatcher(cfg config, seed int64) *dispatcher {
+	if !cfg.deterministic {
+		return nil
+	}
+	return &dispatcher{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// intn returns a pseudo-random number in [0, n).
+func (d *dispatcher) intn(n int) int {
+	return d.rand.Intn(n)
+}
+
+// resolve picks which of the conditions a select should have chosen. The first argument is the index of the channel
+// the select chose. When more than one of the channels is closed, the dispatcher picks one of them instead. A nil
+// dispatcher keeps the select's choice.
+func (d *dispatcher) resolve(first int, chans ...<-chan struct{}) int {
+	if d == nil {
+		return first
+	}
+	var ready []int
+	for i, c := range chans {
+		if dead(c) {
+			ready = append(ready, i)
+		}
+	}
+	if len(ready) < 2 {
+		return first
+	}
+	return ready[d.intn(len(ready))]
+}
@@ -0,0 +1,135 @@
+package ctxerrpool
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// CantDoError is the error for a work item that could not be given to a worker before its context ended. It matches
+// ErrCantDo with errors.Is and unwraps to the reason the context ended. It tells a context that was too short to begin
+// with apart from a Pool that was too busy for too long.
+type CantDoError struct {
+
+	// Cause is the reason the context ended.
+	Cause error
+
+	// Deadline is the deadline the context had, if any. It is the zero time if the context had no deadline.
+	Deadline time.Time
+
+	// LoadFactor is the Pool's load factor when the work item was given up on. See Pool.LoadFactor.
+	LoadFactor float64
+
+	// Waited is how long the work item waited for room in the Pool before it was given up on. It is zero if the context
+	// had already ended when the work item was given to the Pool.
+	Waited time.Duration
+}
+
+// Error implements the error interface.
+func (e *CantDoError) Error() string {
+	return fmt.Sprintf("%v after waiting %v at load factor %.2f: %v", ErrCantDo, e.Waited, e.LoadFactor, e.Cause)
+}
+
+// Is determines if the target is ErrCantDo.
+func (e *CantDoError) Is(target error) bool {
+	return target == ErrCantDo
+}
+
+// Unwrap returns the reason the context ended.
+func (e *CantDoError) Unwrap() error {
+	return e.Cause
+}
+
+// PanicError is the error for a Work function that panicked. It matches ErrWorkPanicked with errors.Is and, if the
+// value given to panic is an error, unwraps to it.
+type PanicError struct {
+
+	// Data is the data that was given with the work item.
+	Data interface{}
+
+	// Recovered is the value given to panic.
+	Recovered interface{}
+
+	// Stack is the stack trace of the goroutine that panicked, captured when the panic was recovered.
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("work item with data %v panicked: %v", e.Data, e.Recovered)
+}
+
+// Format implements the fmt.Formatter interface. The %+v verb adds the stack trace on the lines after the error.
+func (e *PanicError) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		_, _ = fmt.Fprintf(f, "%s\n%s", e.Error(), e.Stack)
+	case verb == 'q':
+		_, _ = fmt.Fprintf(f, "%q", e.Error())
+	default:
+		_, _ = io.WriteString(f, e.Error())
+	}
+}
+
+// Is determines if the target is ErrWorkPanicked.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrWorkPanicked
+}
+
+// PanicStack returns the stack trace of the goroutine that panicked.
+func (e *PanicError) PanicStack() []byte {
+	return e.Stack
+}
+
+// Unwrap returns the value given to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Recovered.(error)
+	return err
+}
+
+// WorkError is an error about a specific work item. It wraps the underlying error and carries the data and labels the
+// work item was given. Every error reported for a work item with labels is a WorkError.
+type WorkError struct {
+
+	// Data is the data that was given with the work item.
+	Data interface{}
+
+	// Err is the underlying error.
+	Err error
+
+	// Labels are the labels that were given with the work item, if any. They must not be modified.
+	Labels map[string]string
+}
+
+// Error implements the error interface.
+func (e *WorkError) Error() string {
+	if len(e.Labels) > 0 {
+		return fmt.Sprintf("work item with data %v and labels %v: %v", e.Data, e.Labels, e.Err)
+	}
+	return fmt.Sprintf("work item with data %v: %v", e.Data, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *WorkError) Unwrap() error {
+	return e.Err
+}
+
+// WorkerError is an error about a specific worker, such as its init hook failing.
+type WorkerError struct {
+
+	// Err is the underlying error.
+	Err error
+
+	// Worker is the number of the worker, starting at 1.
+	Worker uint
+}
+
+// Error implements the error interface.
+func (e *WorkerError) Error() string {
+	return fmt.Sprintf("worker %d: %v", e.Worker, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *WorkerError) Unwrap() error {
+	return e.Err
+}
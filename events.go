@@ -0,0 +1,142 @@
+package ctxerrpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is what happened in an Event.
+type EventType uint8
+
+const (
+
+	// ItemAccepted means the Pool accepted a work item.
+	ItemAccepted EventType = iota
+
+	// ItemStarted means a worker called a work item's Work function.
+	ItemStarted
+
+	// ItemFinished means a work item finished, whether it succeeded, failed, or never ran. Err is the reason it failed,
+	// if any.
+	ItemFinished
+
+	// ItemRejected means a work item was accepted, but then refused because the Pool had no room for it, its context
+	// ended before it could be given to a worker, or the circuit breaker was open. Err is the reason. It takes the
+	// place of ItemFinished.
+	ItemRejected
+
+	// WorkerStarted means a worker got ready and started taking work items.
+	WorkerStarted
+
+	// WorkerStopped means a worker that started stopped taking work items, because the Pool died or it was replaced.
+	WorkerStopped
+
+	// PoolKilled means the Pool died. Err is the reason it died.
+	PoolKilled
+)
+
+// String implements the fmt.Stringer interface.
+func (t EventType) String() string {
+	switch t {
+	case ItemAccepted:
+		return "item accepted"
+	case ItemStarted:
+		return "item started"
+	case ItemFinished:
+		return "item finished"
+	case ItemRejected:
+		return "item rejected"
+	case WorkerStarted:
+		return "worker started"
+	case WorkerStopped:
+		return "worker stopped"
+	case PoolKilled:
+		return "pool killed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is something that happened in a Pool. See Pool.Events.
+type Event struct {
+
+	// At is when it happened, according to the Pool's Clock.
+	At time.Time
+
+	// Dropped is the number of events that were dropped for the subscriber right before this one because its buffer
+	// was full.
+	Dropped uint64
+
+	// Err is the error for ItemFinished, ItemRejected, and PoolKilled events, if any.
+	Err error
+
+	// ID is the ID of the work item for item events.
+	ID WorkID
+
+	// Type is what happened.
+	Type EventType
+
+	// Worker is the number of the worker for worker events and ItemStarted.
+	Worker uint
+}
+
+// events keeps track of the subscribers to a Pool's events.
+type events struct {
+	count int32
+	mux   sync.Mutex
+	subs  map[*subscriber]struct{}
+}
+
+// subscriber is a single stream of events given out by Events.
+type subscriber struct {
+	c       chan Event
+	dropped uint64
+}
+
+// Events subscribes to what happens in the Pool. Every subscriber gets its own stream of events with room for the given
+// number of them. A subscriber that falls behind never holds up the Pool: events that don't fit are dropped and the
+// number dropped is given with the next event that does. The returned function unsubscribes and closes the channel. It
+// may be called more than once. Without subscribers, events cost the Pool next to nothing.
+func (g *Pool) Events(buffer int) (events <-chan Event, cancel func()) {
+	sub := &subscriber{
+		c: make(chan Event, max(buffer, 0)),
+	}
+	g.events.mux.Lock()
+	if g.events.subs == nil {
+		g.events.subs = make(map[*subscriber]struct{})
+	}
+	g.events.subs[sub] = struct{}{}
+	atomic.AddInt32(&g.events.count, 1)
+	g.events.mux.Unlock()
+
+	return sub.c, func() {
+		g.events.mux.Lock()
+		defer g.events.mux.Unlock()
+		if _, ok := g.events.subs[sub]; !ok {
+			return
+		}
+		delete(g.events.subs, sub)
+		atomic.AddInt32(&g.events.count, -1)
+		close(sub.c)
+	}
+}
+
+// emit gives the event to every subscriber that has room for it.
+func (g *Pool) emit(event Event) {
+	if atomic.LoadInt32(&g.events.count) == 0 {
+		return
+	}
+	event.At = g.cfg.clock.Now()
+	g.events.mux.Lock()
+	defer g.events.mux.Unlock()
+	for sub := range g.events.subs {
+		event.Dropped = sub.dropped
+		select {
+		case sub.c <- event:
+			sub.dropped = 0
+		default:
+			sub.dropped++
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestEvents confirms that every subscriber gets the events of a work item's lifecycle, of the workers, and of the
+// Pool's death, in order.
+func TestEvents(t *testing.T) {
+
+	// Create a worker pool with 1 worker that only gets ready once the subscribers are in place.
+	gate := make(chan struct{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {},
+		ctxerrpool.WithWorkerInit(func(worker uint) error {
+			<-gate
+			return nil
+		}))
+	defer pool.Kill()
+
+	// Subscribe twice.
+	first, cancelFirst := pool.Events(100)
+	defer cancelFirst()
+	second, cancelSecond := pool.Events(100)
+	defer cancelSecond()
+	close(gate)
+	<-pool.Started()
+
+	// Give the pool a work item that succeeds, one that fails, and one that finds no room.
+	errWork := errors.New("test")
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	pool.Wait()
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return errWork
+	}, nil)
+	pool.Wait()
+	release := busy(pool)
+	err := pool.AddWorkItemContext(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	release()
+	pool.Wait()
+	if !errors.Is(err, ctxerrpool.ErrQueueFull) {
+		t.Errorf("Expected the work item to find no room, got: %v", err)
+		t.FailNow()
+	}
+	pool.Kill()
+
+	// Confirm both subscribers got the same events.
+	expected := []struct {
+		eventType ctxerrpool.EventType
+		id        ctxerrpool.WorkID
+		err       error
+	}{
+		{eventType: ctxerrpool.WorkerStarted},
+		{eventType: ctxerrpool.ItemAccepted, id: 1},
+		{eventType: ctxerrpool.ItemStarted, id: 1},
+		{eventType: ctxerrpool.ItemFinished, id: 1},
+		{eventType: ctxerrpool.ItemAccepted, id: 2},
+		{eventType: ctxerrpool.ItemStarted, id: 2},
+		{eventType: ctxerrpool.ItemFinished, id: 2, err: errWork},
+		{eventType: ctxerrpool.ItemAccepted, id: 3},
+		{eventType: ctxerrpool.ItemStarted, id: 3},
+		{eventType: ctxerrpool.ItemAccepted, id: 4},
+		{eventType: ctxerrpool.ItemRejected, id: 4, err: ctxerrpool.ErrQueueFull},
+		{eventType: ctxerrpool.ItemFinished, id: 3},
+		{eventType: ctxerrpool.PoolKilled, err: ctxerrpool.ErrPoolKilled},
+	}
+	for _, events := range []<-chan ctxerrpool.Event{first, second} {
+		for _, e := range expected {
+			event := <-events
+			if event.Type != e.eventType || event.ID != e.id || !errors.Is(event.Err, e.err) ||
+				(e.err == nil) != (event.Err == nil) {
+				t.Errorf("Expected %s of work item %d with error %v, got %s of work item %d with error %v.",
+					e.eventType, e.id, e.err, event.Type, event.ID, event.Err)
+				t.FailNow()
+			}
+			if event.At.IsZero() || event.Dropped != 0 {
+				t.Errorf("Unexpected time or dropped count for %s.", event.Type)
+				t.FailNow()
+			}
+		}
+	}
+
+	// Confirm the worker stopped and that unsubscribing closes the channel.
+	select {
+	case event := <-first:
+		if event.Type != ctxerrpool.WorkerStopped || event.Worker != 1 {
+			t.Errorf("Expected the worker to stop, got %s.", event.Type)
+			t.FailNow()
+		}
+	case <-time.After(time.Second):
+		t.Error("The worker did not stop.")
+		t.FailNow()
+	}
+	cancelFirst()
+	cancelFirst()
+	if _, ok := <-first; ok {
+		t.Error("The channel was not closed after unsubscribing.")
+		t.FailNow()
+	}
+}
+
+// TestEventsDropped confirms that a subscriber that falls behind does not hold up the Pool and is told how many events
+// it missed.
+func TestEventsDropped(t *testing.T) {
+
+	// Create a worker pool with 1 worker and wait for it to be ready.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+	<-pool.Started()
+
+	// Subscribe with room for 1 event and give the pool a work item, which makes 3 events.
+	events, cancel := pool.Events(1)
+	defer cancel()
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	pool.Wait()
+
+	// Confirm only the first event was kept and the next one counts the ones that were dropped.
+	if event := <-events; event.Type != ctxerrpool.ItemAccepted || event.Dropped != 0 {
+		t.Errorf("Expected the first event to be kept, got %s after %d dropped.", event.Type, event.Dropped)
+		t.FailNow()
+	}
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	pool.Wait()
+	if event := <-events; event.Type != ctxerrpool.ItemAccepted || event.ID != 2 || event.Dropped != 2 {
+		t.Errorf("Expected work item 2 to be accepted after 2 dropped events, got %s of work item %d after %d dropped.",
+			event.Type, event.ID, event.Dropped)
+		t.FailNow()
+	}
+}
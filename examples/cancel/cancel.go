@@ -16,7 +16,7 @@ func main() {
 
 	// Create an error handler that
 	var errorHandler ctxerrpool.ErrorHandler
-	errorHandler = func(pool ctxerrpool.Pool, err error) {
+	errorHandler = func(pool *ctxerrpool.Pool, err error) {
 		defer errWg.Done()
 		log.Printf("An error occurred. Error: %s\nKilling pool.\n", err.Error())
 		pool.Kill()
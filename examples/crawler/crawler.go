@@ -3,7 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
-	"io/ioutil"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -27,46 +27,15 @@ var (
 	re = regexp.MustCompile(`<a\s+(?:[^>]*?\s+)?href="(.*?)"`)
 )
 
-// createContext creates a context and its cancellation function based on the amount of time scraping should happen.
-func createContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), crawlDuration)
+// crawler holds what every crawling work item needs.
+type crawler struct {
+	httpClient *http.Client
+	l          *log.Logger
 }
 
-// handleHref takes in an href tag and adds it to the upcoming work for the crawler.
-func handleHref(httpClient *http.Client, l *log.Logger, match []byte, pool ctxerrpool.Pool, startU *url.URL) {
-
-	// Get the href's content as an absolute URL.
-	aTag := string(match)
-	split := strings.Split(aTag, `"`)
-	nextURL := split[len(split)-2]
-	nextU, err := url.Parse(nextURL)
-	if err != nil {
-		return
-	}
-	if !nextU.IsAbs() {
-		if nextU, err = startU.Parse(nextU.String()); err != nil {
-			return
-		}
-	}
-
-	// Create a context for the next web crawling request.
-	workerCtx, _ := createContext() // Be careful about shadowing variable names.
-	// It's important to use the context.CancelFunc in production due to resource leaks.
-
-	// Tell the worker pool to crawl to the next page.
-	//
-	// This is an example of how to create a work function via an anonymous function closure.
-	go pool.AddWorkItem(workerCtx, func(workCtx context.Context, data interface{}) error {
-
-		// Do the HTTP request and start crawling. Respect the given context.
-		//
-		// Make sure to use workCtx from anonymous function argument.
-		if err := crawl(workCtx, httpClient, l, pool, data.(string)); err != nil {
-			return err
-		}
-
-		return nil
-	}, nextU.String())
+// createContext creates a context and its cancellation function based on the amount of time scraping should happen.
+func createContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, crawlDuration)
 }
 
 // Don't make a web crawler like this, use github.com/gocolly/colly.
@@ -75,39 +44,31 @@ func main() {
 	// Create a logger.
 	l := log.New(os.Stdout, "", 0)
 
-	// Create an HTTP client.
-	httpClient := &http.Client{}
+	// Create the crawler.
+	c := &crawler{
+		httpClient: &http.Client{},
+		l:          l,
+	}
 
 	// Define the URL string to GET.
 	startURL := "http://golang.org"
 
 	// Create an error handler to log errors.
 	var errorHandler ctxerrpool.ErrorHandler
-	errorHandler = func(pool ctxerrpool.Pool, err error) {
+	errorHandler = func(pool *ctxerrpool.Pool, err error) {
 		l.Printf("An error occurred: \"%v\".\n", err)
 	}
 
 	// Create a worker pool with 4 workers.
 	pool := ctxerrpool.New(4, errorHandler)
 
-	// Create the work function via a closure.
-	var work ctxerrpool.Work
-	work = func(ctx context.Context, data interface{}) (err error) {
-
-		// Do the HTTP request and start crawling. Respect the given context.
-		if err := crawl(ctx, httpClient, l, pool, data.(string)); err != nil {
-			return err
-		}
-
-		return nil
-	}
-
-	// Create a context for the first job.
-	ctx, cancel := createContext()
+	// Create a context for the first job. Its logging fields are shared by every page of the crawl.
+	ctx, cancel := createContext(context.Background())
 	defer cancel()
+	ctx = ctxerrpool.ContextWithLogFields(ctx, map[string]string{"crawl": startURL})
 
-	// Start the scraper.
-	pool.AddWorkItem(ctx, work, startURL)
+	// Start the scraper. The crawl method is given the pool, so it can add follow-up work without a closure.
+	pool.AddPoolWorkItem(ctx, c.crawl, startURL)
 
 	// Wait for the pool to die or for the allowed amount of time to pass.
 	select {
@@ -117,9 +78,11 @@ func main() {
 	}
 }
 
-func crawl(ctx context.Context, httpClient *http.Client, l *log.Logger, pool ctxerrpool.Pool, urlString string) (err error) {
+// crawl is a ctxerrpool.PoolWork that gets the page at the URL given as data and crawls to every page it links to.
+func (c *crawler) crawl(ctx context.Context, pool *ctxerrpool.Pool, data interface{}) (err error) {
 
 	// Make a url.Url from the given string.
+	urlString := data.(string)
 	var startU *url.URL
 	if startU, err = url.Parse(urlString); err != nil {
 		return err
@@ -127,34 +90,63 @@ func crawl(ctx context.Context, httpClient *http.Client, l *log.Logger, pool ctx
 
 	// Create the HTTP request using the context.
 	var req *http.Request
-	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, startU.String(), bytes.NewBuffer([]byte{})); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, startU.String(), bytes.NewReader(nil)); err != nil {
 		return err
 	}
 
 	// Do the HTTP request and get the response.
 	var resp *http.Response
-	if resp, err = httpClient.Do(req); err != nil {
+	if resp, err = c.httpClient.Do(req); err != nil {
 		return err
 	}
 	defer resp.Body.Close() // Ignore any error.
 
 	// Read the body of the response into a variable in the stack.
 	var body []byte
-	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+	if body, err = io.ReadAll(resp.Body); err != nil {
 		return err
 	}
 
-	// Log the page as a success.
-	l.Printf("Successfully retrieved URL: %s\n", urlString)
+	// Log the page as a success along with the logging fields of the crawl.
+	c.l.Printf("Successfully retrieved URL: %s %v\n", urlString, ctxerrpool.LogFields(ctx))
 
 	// Find href tags.
 	if matches := re.FindAll(body, -1); matches != nil {
 
 		// For every match, get its link and crawl to it.
 		for _, match := range matches {
-			handleHref(httpClient, l, match, pool, startU)
+			c.handleHref(ctx, pool, match, startU)
 		}
 	}
 
 	return nil
 }
+
+// handleHref takes in an href tag and adds it to the upcoming work for the crawler.
+func (c *crawler) handleHref(ctx context.Context, pool *ctxerrpool.Pool, match []byte, startU *url.URL) {
+
+	// Get the href's content as an absolute URL.
+	aTag := string(match)
+	split := strings.Split(aTag, `"`)
+	nextURL := split[len(split)-2]
+	nextU, err := url.Parse(nextURL)
+	if err != nil {
+		return
+	}
+	if !nextU.IsAbs() {
+		if nextU, err = startU.Parse(nextU.String()); err != nil {
+			return
+		}
+	}
+
+	// Create a context for the next web crawling request. It is derived from the current work's context without its
+	// cancellation, so the pool knows the work item is re-entrant, but it can outlive the current work.
+	nextCtx, _ := createContext(context.WithoutCancel(ctx))
+	// It's important to use the context.CancelFunc in production due to resource leaks.
+
+	// Record which page linked to the next one. The logging fields of the crawl are inherited.
+	nextCtx = ctxerrpool.ContextWithLogFields(nextCtx, map[string]string{"referrer": startU.String()})
+
+	// Tell the worker pool to crawl to the next page. This is a re-entrant submission, so it will not block.
+	pool.AddPoolWorkItem(nextCtx, c.crawl, nextU.String())
+}
@@ -15,7 +15,7 @@ func main() {
 
 	// Create an error handler that logs all errors.
 	var errorHandler ctxerrpool.ErrorHandler
-	errorHandler = func(pool ctxerrpool.Pool, err error) {
+	errorHandler = func(pool *ctxerrpool.Pool, err error) {
 		log.Printf("An error occurred. Error: \"%s\".\n", err.Error())
 	}
 
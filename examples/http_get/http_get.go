@@ -25,8 +25,7 @@ func main() {
 	urlString := "http://golang.org"
 
 	// Create the work function via a closure.
-	var work ctxerrpool.Work
-	work = func(ctx context.Context, data interface{}) (err error) {
+	work := func(ctx context.Context) (err error) {
 
 		// Do the HTTP request, respect the given context.
 		body, err := makeRequest(ctx, httpClient, urlString)
@@ -42,7 +41,7 @@ func main() {
 
 	// Create an error handler to log errors.
 	var errorHandler ctxerrpool.ErrorHandler
-	errorHandler = func(pool ctxerrpool.Pool, err error) {
+	errorHandler = func(pool *ctxerrpool.Pool, err error) {
 		l.Printf("An error occurred: \"%v\".\n", err)
 	}
 
@@ -54,7 +53,9 @@ func main() {
 	defer cancel()
 
 	// Give the pool some work to do.
-	pool.AddWorkItem(ctx, work, "")
+	if err := pool.Go(ctx, work); err != nil {
+		l.Fatalf("The work was not accepted: \"%v\".\n", err)
+	}
 
 	// Wait for the worker pool to be done working.
 	pool.Wait()
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"ctxerrpool"
+)
+
+func main() {
+
+	// Create an error handler that ignores errors. The progress bar shows how many failed.
+	var errorHandler ctxerrpool.ErrorHandler
+	errorHandler = func(pool *ctxerrpool.Pool, err error) {}
+
+	// Create a worker pool with 4 workers.
+	pool := ctxerrpool.New(4, errorHandler)
+	defer pool.Kill()
+
+	// Create a batch of work that draws a progress bar every time a work item finishes.
+	const total = 200
+	batch := pool.NewBatch(total, func(completed, failed, remaining int64) {
+		const width = 40
+		filled := int(completed * width / total)
+		bar := make([]byte, width)
+		for i := range bar {
+			if i < filled {
+				bar[i] = '#'
+			} else {
+				bar[i] = '.'
+			}
+		}
+		fmt.Printf("\r[%s] %d/%d done, %d failed", bar, completed, total, failed)
+	})
+
+	// Create the worker function. It sometimes fails.
+	var work ctxerrpool.Work
+	work = func(ctx context.Context, data interface{}) (err error) {
+		select {
+		case <-time.After(time.Duration(rand.Intn(20)) * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if rand.Intn(10) == 0 {
+			return errors.New("unlucky")
+		}
+		return nil
+	}
+
+	// Create a context for the work.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Do the work.
+	for i := 0; i < total; i++ {
+		batch.AddWorkItem(ctx, work, i)
+	}
+
+	// Wait for the pool to finish.
+	pool.Wait()
+	fmt.Println()
+	log.Printf("%d work items failed.", batch.Failed())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"ctxerrpool"
+)
+
+func main() {
+
+	// Create an error handler that logs all errors.
+	var errorHandler ctxerrpool.ErrorHandler
+	errorHandler = func(pool *ctxerrpool.Pool, err error) {
+		log.Printf("An error occurred. Error: \"%s\".\n", err.Error())
+	}
+
+	// Create a worker pool with 4 workers and a small queue.
+	pool := ctxerrpool.New(4, errorHandler, ctxerrpool.WithQueueSize(4))
+	defer pool.Kill()
+
+	// Create the worker function. It takes a little while.
+	var work ctxerrpool.Work
+	work = func(ctx context.Context, data interface{}) (err error) {
+		select {
+		case <-time.After(time.Duration(rand.Intn(20)) * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	// Create a context for the work.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Produce work faster than the pool can do it. Every other work item is low-value and is dropped when the pool is
+	// more than 80% loaded.
+	const threshold = 0.8
+	shed := 0
+	for i := 0; i < 200; i++ {
+		lowValue := i%2 == 1
+		if lowValue && pool.LoadFactor() > threshold {
+			shed++
+			continue
+		}
+		pool.AddWorkItem(ctx, work, i)
+	}
+
+	// Wait for the pool to finish.
+	pool.Wait()
+	log.Printf("Dropped %d low-value work items to keep up.", shed)
+}
@@ -0,0 +1,17 @@
+package ctxerrpool
+
+import (
+	"sync/atomic"
+)
+
+// Awaiting returns the number of channels returned by Await that the Pool still tracks.
+func (g *Pool) Awaiting() int64 {
+	return atomic.LoadInt64(&g.awaiting)
+}
+
+// Blocked returns the number of work items waiting for room in the queue.
+func (g *Pool) Blocked() int {
+	g.queue.mux.Lock()
+	defer g.queue.mux.Unlock()
+	return len(g.queue.blocked)
+}
@@ -0,0 +1,29 @@
+package ctxerrpool
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+)
+
+// ErrExpvarExists indicates that an expvar is already published under the given name.
+var ErrExpvarExists = errors.New("an expvar is already published under the name")
+
+// expvarMux makes checking for and publishing an expvar one step.
+var expvarMux sync.Mutex
+
+// PublishExpvar publishes the Pool's Stats under the given name with the expvar package, so they are served as JSON at
+// /debug/vars along with the other expvars. The Stats are read when the expvar is, so scraping them costs nothing in
+// between. ErrExpvarExists is returned if anything is already published under the name. The expvar package can't
+// remove a published expvar, so the name stays taken and keeps reporting the Pool after it dies.
+func (g *Pool) PublishExpvar(name string) error {
+	expvarMux.Lock()
+	defer expvarMux.Unlock()
+	if expvar.Get(name) != nil {
+		return ErrExpvarExists
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return g.Stats()
+	}))
+	return nil
+}
@@ -0,0 +1,54 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestPublishExpvar confirms that PublishExpvar publishes the Pool's Stats as JSON and refuses a name that is taken.
+func TestPublishExpvar(t *testing.T) {
+
+	// Create a worker pool with 1 worker and publish it. Published names can't be taken back, so each run uses its own.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+	name := fmt.Sprintf("TestPublishExpvar%d", time.Now().UnixNano())
+	if err := pool.PublishExpvar(name); err != nil {
+		t.Errorf("Failed to publish the pool. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Keep the worker busy and have a work item rejected.
+	release := busy(pool)
+	err := pool.AddWorkItemContext(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	if !errors.Is(err, ctxerrpool.ErrQueueFull) {
+		t.Errorf("Expected the work item to find no room, got: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the published Stats are read when the expvar is.
+	var stats ctxerrpool.Stats
+	if err = json.Unmarshal([]byte(expvar.Get(name).String()), &stats); err != nil {
+		t.Errorf("Failed to decode the published stats. Error: %v", err)
+		t.FailNow()
+	}
+	release()
+	if stats.Rejected != 1 || stats.Running != 1 {
+		t.Errorf("Unexpected published stats.\nRejected: %d\nRunning: %d", stats.Rejected, stats.Running)
+		t.FailNow()
+	}
+
+	// Confirm the name can't be taken twice.
+	if err = pool.PublishExpvar(name); !errors.Is(err, ctxerrpool.ErrExpvarExists) {
+		t.Errorf("Expected the name to be taken, got: %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,32 @@
+package ctxerrpool
+
+import (
+	"context"
+	"maps"
+)
+
+// fieldsKey is the context key for the logging fields carried by a context.
+type fieldsKey struct{}
+
+// ContextWithLogFields returns a copy of the context that carries the given logging fields on top of the ones it
+// already carries, if any. Fields with the same key replace the inherited ones. The fields are copied, so changing the
+// map afterwards has no effect.
+//
+// The fields reach the Work function through its context and, since re-entrant submissions are made with that
+// context or one derived from it, every work item it adds inherits them too. A whole tree of recursive work, such as a
+// crawl, can share correlation fields this way, and each level can extend them.
+func ContextWithLogFields(ctx context.Context, fields map[string]string) context.Context {
+	merged := maps.Clone(LogFields(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(fields))
+	}
+	maps.Copy(merged, fields)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// LogFields returns the logging fields carried by the context, or nil if there are none. The map must not be modified.
+// Use ContextWithLogFields to extend it.
+func LogFields(ctx context.Context) map[string]string {
+	fields, _ := ctx.Value(fieldsKey{}).(map[string]string)
+	return fields
+}
@@ -0,0 +1,68 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestLogFields confirms that logging fields reach the Work function and are inherited and extended by the work items
+// it adds.
+func TestLogFields(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep track of the fields each work item saw and a mutex for them.
+	mux := &sync.Mutex{}
+	seen := make(map[string]map[string]string)
+
+	// Give the pool a work item with fields that adds a child with more fields.
+	_, err := pool.Submit(ctx, func(workCtx context.Context, data interface{}) error {
+		mux.Lock()
+		seen["parent"] = ctxerrpool.LogFields(workCtx)
+		mux.Unlock()
+		childCtx := ctxerrpool.ContextWithLogFields(context.WithoutCancel(workCtx), map[string]string{"depth": "1"})
+		pool.AddWorkItem(childCtx, func(workCtx context.Context, data interface{}) error {
+			mux.Lock()
+			seen["child"] = ctxerrpool.LogFields(workCtx)
+			mux.Unlock()
+			return nil
+		}, nil)
+		return nil
+	}, ctxerrpool.WithLogFields(map[string]string{"crawl": "42", "depth": "0"}))
+	if err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	pool.Wait()
+
+	// Confirm the parent saw its fields and the child inherited and extended them.
+	mux.Lock()
+	defer mux.Unlock()
+	parent, child := seen["parent"], seen["child"]
+	if len(parent) != 2 || parent["crawl"] != "42" || parent["depth"] != "0" {
+		t.Errorf("The parent saw the wrong fields: %v", parent)
+		t.FailNow()
+	}
+	if len(child) != 2 || child["crawl"] != "42" || child["depth"] != "1" {
+		t.Errorf("The child saw the wrong fields: %v", child)
+		t.FailNow()
+	}
+
+	// Confirm a context without fields has none.
+	if fields := ctxerrpool.LogFields(ctx); fields != nil {
+		t.Errorf("A context without fields had fields: %v", fields)
+		t.FailNow()
+	}
+}
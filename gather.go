@@ -0,0 +1,118 @@
+package ctxerrpool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Gather gives every function to the Pool under the given context and waits up to the timeout for them to return. It
+// returns the values and errors of the functions, each at the same index as its function. A function's value is the
+// zero value if it returned an error. A function that did not return in time has the zero value and
+// context.DeadlineExceeded, or the context's error if the context ended first. Its context is canceled and whatever it
+// returns later is discarded, including any error, which is not reported to the error handler. The errors of functions
+// that returned in time are reported to the error handler as usual. Zero means there is no timeout.
+//
+// Gather is meant for scattering a query to several backends and using whatever came back in time. The late work items
+// still finish through the Pool as usual, so Wait accounts for them. The functions are given to the Pool from another
+// goroutine, which blocks like AddWorkItem does, so time spent waiting for room in the queue counts towards the
+// timeout. It panics with ErrNilWork if any of the functions is nil.
+func Gather[T any](ctx context.Context, pool *Pool, timeout time.Duration,
+	fns ...func(context.Context) (T, error)) ([]T, []error) {
+	for _, fn := range fns {
+		if fn == nil {
+			panic(ErrNilWork)
+		}
+	}
+	values := make([]T, len(fns))
+	errs := make([]error, len(fns))
+	if len(fns) == 0 {
+		return values, errs
+	}
+
+	// Cancel the late functions when Gather returns.
+	gatherCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Keep track of which functions returned in time and a mutex for them.
+	mux := &sync.Mutex{}
+	all := make(chan struct{})
+	late := false
+	returned := make([]bool, len(fns))
+	remaining := len(fns)
+
+	// record keeps the outcome of the function at the index if it is the first one for it and it is in time.
+	record := func(i int, value T, err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		if late || returned[i] {
+			return
+		}
+		returned[i] = true
+		errs[i] = err
+		if err == nil {
+			values[i] = value
+		}
+		if remaining--; remaining == 0 {
+			close(all)
+		}
+	}
+
+	// Give every function to the pool. A work item that never runs is recorded when it finishes.
+	submit := func(i int, fn func(context.Context) (T, error)) {
+		pool.addWorkItem(gatherCtx, &workItem{
+			handler: func(g *Pool, err error) {
+				mux.Lock()
+				inTime := returned[i] || !late
+				mux.Unlock()
+				if inTime {
+					g.handler(g, err)
+				}
+			},
+			onFinish: func(err error) {
+				var zero T
+				record(i, zero, err)
+			},
+			work: func(workCtx context.Context, data interface{}) error {
+				value, err := fn(workCtx)
+				record(i, value, err)
+				return err
+			},
+			data: i,
+		})
+	}
+	pool.spawn(func() {
+		for i, fn := range fns {
+			submit(i, fn)
+		}
+	})
+
+	// Wait for every function to return, the timeout, or the context.
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := pool.cfg.clock.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C()
+	}
+	var err error
+	select {
+	case <-all:
+		return values, errs
+	case <-expired:
+		err = context.DeadlineExceeded
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// Give up on the functions that have not returned.
+	mux.Lock()
+	defer mux.Unlock()
+	late = true
+	for i := range fns {
+		if !returned[i] {
+			errs[i] = err
+		}
+	}
+
+	return values, errs
+}
@@ -0,0 +1,92 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestGather confirms that Gather returns the results that arrived in time, gives up on the late ones, and cancels
+// them.
+func TestGather(t *testing.T) {
+
+	// Create an error for the function that fails.
+	errFailed := errors.New("failed")
+
+	// Keep track of the errors that were handled and a mutex for them.
+	mux := &sync.Mutex{}
+	var handled []error
+
+	// Create a worker pool with 3 workers.
+	pool := ctxerrpool.New(3, func(pool *ctxerrpool.Pool, err error) {
+		mux.Lock()
+		handled = append(handled, err)
+		mux.Unlock()
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Gather from a function that succeeds, one that fails, and one that does not return in time.
+	canceled := make(chan struct{})
+	values, errs := ctxerrpool.Gather(ctx, pool, 50*time.Millisecond, func(ctx context.Context) (int, error) {
+		return 1, nil
+	}, func(ctx context.Context) (int, error) {
+		return 2, errFailed
+	}, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(canceled)
+		return 3, errors.New("late")
+	})
+
+	// Confirm the results are aligned with the functions.
+	if len(values) != 3 || values[0] != 1 || values[1] != 0 || values[2] != 0 {
+		t.Errorf("Unexpected values: %v.", values)
+		t.FailNow()
+	}
+	if len(errs) != 3 || errs[0] != nil || !errors.Is(errs[1], errFailed) ||
+		!errors.Is(errs[2], context.DeadlineExceeded) {
+		t.Errorf("Unexpected errors: %v.", errs)
+		t.FailNow()
+	}
+
+	// Confirm the late function was canceled and still finished through the pool.
+	<-canceled
+	settle(t, pool)
+
+	// Confirm only the error of the function that returned in time was handled.
+	mux.Lock()
+	defer mux.Unlock()
+	if len(handled) != 1 || !errors.Is(handled[0], errFailed) {
+		t.Errorf("Expected only the failed function's error to be handled. Errors: %v", handled)
+		t.FailNow()
+	}
+}
+
+// TestGatherAll confirms that Gather returns as soon as every function has returned.
+func TestGatherAll(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	})
+	defer pool.Kill()
+
+	// Gather without a timeout.
+	values, errs := ctxerrpool.Gather(context.Background(), pool, 0, func(ctx context.Context) (string, error) {
+		return "a", nil
+	}, func(ctx context.Context) (string, error) {
+		return "b", nil
+	})
+	if len(values) != 2 || values[0] != "a" || values[1] != "b" || errs[0] != nil || errs[1] != nil {
+		t.Errorf("Unexpected results. Values: %v. Errors: %v", values, errs)
+		t.FailNow()
+	}
+}
@@ -1,3 +1,3 @@
 module ctxerrpool
 
-go 1.17
+go 1.21
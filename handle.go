@@ -0,0 +1,139 @@
+package ctxerrpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WorkState is where a work item is in its lifecycle. See Handle.
+type WorkState uint32
+
+const (
+
+	// StatePending means the work item has not been given to a Pool yet.
+	StatePending WorkState = iota
+
+	// StateQueued means the work item was accepted by a Pool and is waiting for a worker, including waiting for room
+	// in the queue or to be requeued.
+	StateQueued
+
+	// StateRunning means the work item's Work function was called.
+	StateRunning
+
+	// StateSucceeded means the work item's Work function returned without an error.
+	StateSucceeded
+
+	// StateFailed means the work item failed after its Work function was called.
+	StateFailed
+
+	// StateRejected means the work item never ran because the Pool had no room for it, its context ended before it
+	// could be given to a worker, or the circuit breaker was open.
+	StateRejected
+
+	// StateDropped means the work item never ran for any other reason, such as the Pool being killed, the work item
+	// being canceled, purged, or evicted, or its context ending while it was queued.
+	StateDropped
+
+	// states is the number of states.
+	states
+)
+
+// Done determines if the state is final.
+func (s WorkState) Done() bool {
+	return s >= StateSucceeded && s < states
+}
+
+// String implements the fmt.Stringer interface.
+func (s WorkState) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateQueued:
+		return "queued"
+	case StateRunning:
+		return "running"
+	case StateSucceeded:
+		return "succeeded"
+	case StateFailed:
+		return "failed"
+	case StateRejected:
+		return "rejected"
+	case StateDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// Handle follows a single work item given to Submit with WithHandle through its lifecycle. The zero value is ready to
+// use. A Handle must only be given to one work item.
+type Handle struct {
+	id    uint64
+	mux   sync.Mutex
+	state uint32
+	times [states]time.Time
+}
+
+// ID returns the ID of the work item. It is zero until the work item was accepted by a Pool.
+func (h *Handle) ID() WorkID {
+	return WorkID(atomic.LoadUint64(&h.id))
+}
+
+// State returns where the work item is in its lifecycle.
+func (h *Handle) State() WorkState {
+	return WorkState(atomic.LoadUint32(&h.state))
+}
+
+// At returns when the work item last entered the state, according to the Pool's Clock. It is the zero time if the work
+// item never did.
+func (h *Handle) At(state WorkState) time.Time {
+	if state >= states {
+		return time.Time{}
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.times[state]
+}
+
+// accept records the ID of the work item and moves it to StateQueued at the given time.
+func (h *Handle) accept(id WorkID, at time.Time) {
+	if h == nil {
+		return
+	}
+	atomic.StoreUint64(&h.id, uint64(id))
+	h.enter(StateQueued, at)
+}
+
+// enter moves the work item to the state at the given time. It does nothing if there is no Handle or the work item is
+// in a final state.
+func (h *Handle) enter(state WorkState, at time.Time) {
+	if h == nil {
+		return
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.State().Done() {
+		return
+	}
+	h.times[state] = at
+	atomic.StoreUint32(&h.state, uint32(state))
+}
+
+// finish moves the work item to its final state at the given time, based on the error that ended it and whether its
+// Work function was called.
+func (h *Handle) finish(err error, at time.Time) {
+	if h == nil {
+		return
+	}
+	state := StateDropped
+	switch {
+	case h.State() == StateRunning && err == nil:
+		state = StateSucceeded
+	case h.State() == StateRunning:
+		state = StateFailed
+	case rejected(err):
+		state = StateRejected
+	}
+	h.enter(state, at)
+}
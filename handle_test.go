@@ -0,0 +1,138 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestHandle confirms that every path a work item can take ends in the expected final state with the expected
+// transitions recorded.
+func TestHandle(t *testing.T) {
+
+	// Create Work functions that succeed and fail.
+	succeed := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	fail := func(workCtx context.Context, data interface{}) error {
+		return errors.New("test")
+	}
+
+	testCases := []struct {
+		name     string
+		expected ctxerrpool.WorkState
+		ran      bool
+		submit   func(pool *ctxerrpool.Pool, handle *ctxerrpool.Handle)
+	}{
+		{
+			name:     "succeeded",
+			expected: ctxerrpool.StateSucceeded,
+			ran:      true,
+			submit: func(pool *ctxerrpool.Pool, handle *ctxerrpool.Handle) {
+				_, _ = pool.Submit(context.Background(), succeed, ctxerrpool.WithHandle(handle))
+			},
+		},
+		{
+			name:     "failed",
+			expected: ctxerrpool.StateFailed,
+			ran:      true,
+			submit: func(pool *ctxerrpool.Pool, handle *ctxerrpool.Handle) {
+				_, _ = pool.Submit(context.Background(), fail, ctxerrpool.WithHandle(handle))
+			},
+		},
+		{
+			name:     "rejected",
+			expected: ctxerrpool.StateRejected,
+			submit: func(pool *ctxerrpool.Pool, handle *ctxerrpool.Handle) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, _ = pool.Submit(ctx, succeed, ctxerrpool.WithHandle(handle))
+			},
+		},
+		{
+			name:     "canceled",
+			expected: ctxerrpool.StateDropped,
+			submit: func(pool *ctxerrpool.Pool, handle *ctxerrpool.Handle) {
+				release := busy(pool)
+				defer release()
+				id, _ := pool.Submit(context.Background(), succeed, ctxerrpool.WithHandle(handle))
+				pool.Cancel(id)
+			},
+		},
+		{
+			name:     "purged",
+			expected: ctxerrpool.StateDropped,
+			submit: func(pool *ctxerrpool.Pool, handle *ctxerrpool.Handle) {
+				release := busy(pool)
+				defer release()
+				_, _ = pool.Submit(context.Background(), succeed, ctxerrpool.WithHandle(handle))
+				pool.PurgeQueue()
+			},
+		},
+		{
+			name:     "killed",
+			expected: ctxerrpool.StateDropped,
+			submit: func(pool *ctxerrpool.Pool, handle *ctxerrpool.Handle) {
+				pool.Kill()
+				_, _ = pool.Submit(context.Background(), succeed, ctxerrpool.WithHandle(handle))
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			// Create a worker pool with 1 worker and room for 1 queued work item.
+			pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(1))
+			defer pool.Kill()
+
+			// Follow a work item down the path of the test case.
+			handle := &ctxerrpool.Handle{}
+			if state := handle.State(); state != ctxerrpool.StatePending {
+				t.Errorf("Expected a new handle to be pending, but it was %s.", state)
+				t.FailNow()
+			}
+			testCase.submit(pool, handle)
+			pool.Wait()
+
+			// Confirm the final state and the transitions that led to it.
+			if state := handle.State(); state != testCase.expected || !state.Done() {
+				t.Errorf("Expected the work item to be %s, but it was %s.", testCase.expected, state)
+				t.FailNow()
+			}
+			if handle.At(testCase.expected).IsZero() {
+				t.Errorf("The time the work item was %s was not recorded.", testCase.expected)
+				t.FailNow()
+			}
+			if ran := !handle.At(ctxerrpool.StateRunning).IsZero(); ran != testCase.ran {
+				t.Errorf("Expected the work item to have run: %t, but it was: %t.", testCase.ran, ran)
+				t.FailNow()
+			}
+			if accepted := handle.ID() != 0; accepted != !handle.At(ctxerrpool.StateQueued).IsZero() {
+				t.Error("The work item's ID and the time it was queued do not agree.")
+				t.FailNow()
+			}
+		})
+	}
+}
+
+// busy keeps the only worker of the pool busy until the returned function is called.
+func busy(pool *ctxerrpool.Pool) (release func()) {
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		close(started)
+		select {
+		case <-gate:
+		case <-time.After(time.Second):
+		}
+		return nil
+	}, nil)
+	<-started
+	return func() {
+		close(gate)
+	}
+}
@@ -0,0 +1,194 @@
+package ctxerrpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hedge tracks the attempts of a work item given to SubmitHedged. The first attempt to finish wins.
+type hedge struct {
+	callback func(err error)
+	done     chan struct{}
+	ids      map[int]WorkID
+	mux      sync.Mutex
+	pool     *Pool
+	winner   int
+}
+
+// SubmitHedged is like Submit, but if the work item has not finished after the hedge delay, a copy of it is given to
+// the Pool, up to maxHedges copies, each one a hedge delay after the last. The first attempt to finish wins, whether it
+// succeeded or failed, and the contexts of the other attempts are canceled like Cancel does. Use it for work with a
+// long tail of latency, such as requests to replicated services, where a second try is often faster than waiting.
+//
+// Only the winner's error is reported to the error handler and given to the callback set with WithCallback, which is
+// called exactly once. The errors of the other attempts, including context.Canceled, are discarded. Every attempt
+// occupies a worker and counts towards Wait like any work item, and the copies are counted in Stats as Hedged. A copy
+// is only given to the Pool if it has room for it right away, so hedging never blocks. Other options apply to every
+// attempt separately, so WithTimeout limits each attempt and WithRetryPolicy retries within each attempt.
+//
+// The returned ID is the first attempt's. The errors returned are the same as for Submit, along with one matching
+// ErrInvalidSubmitOption if the hedge delay or maxHedges is negative.
+func (g *Pool) SubmitHedged(ctx context.Context, work Work, hedgeDelay time.Duration, maxHedges int,
+	opts ...SubmitOption) (WorkID, error) {
+
+	// Refuse a nil Work function.
+	if work == nil {
+		return 0, ErrNilWork
+	}
+
+	// Apply and check the options.
+	s, err := newSubmission(opts)
+	if err != nil {
+		return 0, err
+	}
+	if hedgeDelay < 0 || maxHedges < 0 {
+		return 0, fmt.Errorf("%w: the hedge delay and maximum hedges must not be negative", ErrInvalidSubmitOption)
+	}
+
+	// Only the winner calls the callback.
+	h := &hedge{
+		callback: s.callback,
+		done:     make(chan struct{}),
+		ids:      make(map[int]WorkID),
+		pool:     g,
+		winner:   -1,
+	}
+	s.callback = nil
+	handle := s.handle
+	s.handle = nil
+
+	// Give the first attempt to the Pool.
+	attemptCtx, item := g.prepare(ctx, work, s)
+	item.handle = handle
+	h.attempt(item, 0)
+	id := g.addWorkItem(attemptCtx, item)
+	if id == 0 {
+		return 0, g.killCause()
+	}
+	if !h.started(0, id) || maxHedges == 0 {
+		return id, nil
+	}
+
+	// Give a copy of the work item to the Pool after every hedge delay until an attempt finishes.
+	g.spawn(func() {
+		for attempt := 1; attempt <= maxHedges; attempt++ {
+			timer := g.cfg.clock.NewTimer(hedgeDelay)
+			select {
+			case <-timer.C():
+			case <-h.done:
+				timer.Stop()
+				return
+			case <-g.death:
+				timer.Stop()
+				return
+			}
+			attemptCtx, item := g.prepare(ctx, work, s)
+			h.attempt(item, attempt)
+			if !h.launch(attemptCtx, item, attempt) {
+				return
+			}
+		}
+	})
+
+	return id, nil
+}
+
+// attempt makes the work item the given attempt of the hedge, so it competes with the other attempts.
+func (h *hedge) attempt(item *workItem, attempt int) {
+	g := h.pool
+
+	// The attempt finishes when its Work function returns, even if the worker has not finished it yet.
+	work := item.work
+	item.work = func(workCtx context.Context, data interface{}) error {
+		err := work(workCtx, data)
+		h.win(attempt)
+		return err
+	}
+
+	// Only report the winner's errors, to the work item's own error handler if it has one.
+	handler := item.handler
+	if handler == nil {
+		handler = g.handler
+	}
+	item.handler = func(pool *Pool, err error) {
+		if h.win(attempt) {
+			handler(pool, err)
+		}
+	}
+
+	// Only the winner calls the callback.
+	item.onFinish = func(err error) {
+		if h.win(attempt) && h.callback != nil {
+			h.callback(err)
+		}
+	}
+}
+
+// launch gives a copy of the work item to the Pool if no attempt has finished and the Pool has room for it right away.
+// It returns false if no more copies should be given to the Pool.
+func (h *hedge) launch(ctx context.Context, item *workItem, attempt int) bool {
+	g := h.pool
+
+	// Accept the copy while no attempt has finished, so Wait can't return before it is queued.
+	h.mux.Lock()
+	if h.winner != -1 || g.Dead() || expired(ctx) != nil {
+		h.mux.Unlock()
+		if item.release != nil {
+			item.release()
+		}
+		return false
+	}
+	reentrant := g.accept(ctx, item)
+	h.mux.Unlock()
+
+	// Try to queue the copy once. A copy the Pool has no room for is skipped.
+	result, evicted, _ := g.queue.push(item, reentrant)
+	if !g.queued(result, item, evicted) {
+		g.unaccept(item)
+		return result != pushDead
+	}
+	atomic.AddUint64(&g.stats.submitted, 1)
+	atomic.AddUint64(&g.stats.hedged, 1)
+
+	return h.started(attempt, WorkID(item.seq))
+}
+
+// started records the ID of an attempt given to the Pool. If an attempt already finished, the new one is canceled and
+// false is returned.
+func (h *hedge) started(attempt int, id WorkID) bool {
+	h.mux.Lock()
+	if winner := h.winner; winner != -1 {
+		h.mux.Unlock()
+		if winner != attempt {
+			h.pool.Cancel(id)
+		}
+		return false
+	}
+	h.ids[attempt] = id
+	h.mux.Unlock()
+	return true
+}
+
+// win makes the attempt the winner if no attempt has finished yet and cancels the other attempts. It returns true if
+// the attempt is the winner.
+func (h *hedge) win(attempt int) bool {
+	h.mux.Lock()
+	if h.winner != -1 {
+		h.mux.Unlock()
+		return h.winner == attempt
+	}
+	h.winner = attempt
+	close(h.done)
+	delete(h.ids, attempt)
+	h.mux.Unlock()
+
+	// Cancel the other attempts. Canceling a queued attempt finishes it, which needs the lock.
+	for _, id := range h.ids {
+		h.pool.Cancel(id)
+	}
+
+	return true
+}
@@ -0,0 +1,149 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestSubmitHedged confirms that a slow work item is hedged, the first attempt to finish wins, and only the winner's
+// error is reported.
+func TestSubmitHedged(t *testing.T) {
+
+	// Create an error for the winning attempt.
+	errWinner := errors.New("winner")
+
+	// Keep track of the errors that were handled and a mutex for them.
+	mux := &sync.Mutex{}
+	var handled []error
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		mux.Lock()
+		handled = append(handled, err)
+		mux.Unlock()
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The first attempt hangs until it is canceled, the second one fails right away.
+	var attempts int32
+	canceled := make(chan struct{})
+	work := func(workCtx context.Context, data interface{}) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			<-workCtx.Done()
+			close(canceled)
+			return errors.New("loser")
+		}
+		return errWinner
+	}
+
+	// Hedge the work item up to 3 times and record the error given to the callback.
+	finished := make(chan error, 2)
+	_, err := pool.SubmitHedged(ctx, work, 10*time.Millisecond, 3, ctxerrpool.WithCallback(func(err error) {
+		finished <- err
+	}))
+	if err != nil {
+		t.Errorf("The work item was not accepted. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the winner's error was given to the callback and the loser was canceled.
+	if err = <-finished; !errors.Is(err, errWinner) {
+		t.Errorf("Expected the winner's error to be given to the callback. Error: %v", err)
+		t.FailNow()
+	}
+	<-canceled
+	settle(t, pool)
+
+	// Confirm only the winner's error was handled, once.
+	mux.Lock()
+	if len(handled) != 1 || !errors.Is(handled[0], errWinner) {
+		t.Errorf("Expected only the winner's error to be handled. Errors: %v", handled)
+		t.FailNow()
+	}
+	mux.Unlock()
+
+	// Confirm the callback was called once and only one copy was made.
+	if len(finished) != 0 {
+		t.Error("The callback was called more than once.")
+		t.FailNow()
+	}
+	if stats := pool.Stats(); stats.Hedged != 1 || atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected 1 copy of the work item. Hedged: %d. Attempts: %d", stats.Hedged, attempts)
+		t.FailNow()
+	}
+}
+
+// TestSubmitHedgedInvalid confirms that negative hedging settings are rejected.
+func TestSubmitHedgedInvalid(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	})
+	defer pool.Kill()
+
+	// Confirm negative settings are rejected.
+	work := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	if _, err := pool.SubmitHedged(context.Background(), work, -time.Second, 1); !errors.Is(err,
+		ctxerrpool.ErrInvalidSubmitOption) {
+		t.Errorf("Expected an invalid option error. Error: %v", err)
+		t.FailNow()
+	}
+	if _, err := pool.SubmitHedged(context.Background(), work, time.Second, -1); !errors.Is(err,
+		ctxerrpool.ErrInvalidSubmitOption) {
+		t.Errorf("Expected an invalid option error. Error: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestSubmitHedgedErrorHandler confirms that the winner's error goes to the error handler given with WithErrorHandler
+// instead of the Pool's.
+func TestSubmitHedgedErrorHandler(t *testing.T) {
+
+	// Create a worker pool with 2 workers whose error handler must not be called.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("The Pool's error handler was called. Error: %v", err)
+	})
+	defer pool.Kill()
+
+	// Create an error for the work item.
+	errWork := errors.New("work")
+
+	// Hedge a failing work item with its own error handler.
+	handled := make(chan error, 2)
+	work := func(workCtx context.Context, data interface{}) error {
+		return errWork
+	}
+	_, err := pool.SubmitHedged(context.Background(), work, time.Second, 1,
+		ctxerrpool.WithErrorHandler(func(pool *ctxerrpool.Pool, err error) {
+			handled <- err
+		}))
+	if err != nil {
+		t.Errorf("The work item was not accepted. Error: %v", err)
+		t.FailNow()
+	}
+	settle(t, pool)
+
+	// Confirm only the work item's error handler got the error, once.
+	if err = <-handled; !errors.Is(err, errWork) {
+		t.Errorf("Expected the work item's error to be handled. Error: %v", err)
+		t.FailNow()
+	}
+	if len(handled) != 0 {
+		t.Error("The error was handled more than once.")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,59 @@
+package ctxerrpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// idlers keeps track of the functions given to OnIdle.
+type idlers struct {
+	count int32
+	fns   map[*func()]struct{}
+	mux   sync.Mutex
+}
+
+// OnIdle calls the function every time the number of pending work items, as told by PendingCount, drops to zero. No
+// drop is missed, even if work items are given to the Pool right after it. Each drop calls the function once in a new
+// goroutine, so going from 1 to 0 to 1 to 0 quickly calls it twice, and the calls may overlap. A work item that is
+// refused without being queued, such as by AddWorkItemContext, is pending for a moment, so it may cause a drop too. The
+// returned function stops the calls that have not started yet. It may be called more than once.
+func (g *Pool) OnIdle(fn func()) (cancel func()) {
+	key := &fn
+	g.idlers.mux.Lock()
+	if g.idlers.fns == nil {
+		g.idlers.fns = make(map[*func()]struct{})
+	}
+	g.idlers.fns[key] = struct{}{}
+	atomic.AddInt32(&g.idlers.count, 1)
+	g.idlers.mux.Unlock()
+
+	return func() {
+		g.idlers.mux.Lock()
+		defer g.idlers.mux.Unlock()
+		if _, ok := g.idlers.fns[key]; ok {
+			delete(g.idlers.fns, key)
+			atomic.AddInt32(&g.idlers.count, -1)
+		}
+	}
+}
+
+// idle calls the functions given to OnIdle, each in a new goroutine. It is called every time the number of pending
+// work items drops to zero and does nothing if there are none.
+func (g *Pool) idle() {
+	if atomic.LoadInt32(&g.idlers.count) == 0 {
+		return
+	}
+	g.idlers.mux.Lock()
+	defer g.idlers.mux.Unlock()
+	for key := range g.idlers.fns {
+		key := key
+		g.spawn(func() {
+			g.idlers.mux.Lock()
+			_, ok := g.idlers.fns[key]
+			g.idlers.mux.Unlock()
+			if ok {
+				(*key)()
+			}
+		})
+	}
+}
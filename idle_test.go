@@ -0,0 +1,73 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestOnIdle confirms that OnIdle is called every time the Pool runs out of pending work items and not after
+// unsubscribing.
+func TestOnIdle(t *testing.T) {
+
+	// Create a worker pool with 2 workers and count the times it goes idle.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+	var idle int64
+	cancel := pool.OnIdle(func() {
+		atomic.AddInt64(&idle, 1)
+	})
+
+	// Create a context.
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second)
+	defer cancelCtx()
+
+	// Go idle 3 times, the first after several work items at once.
+	work := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	gate := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		<-gate
+		return nil
+	}, nil)
+	for i := 0; i < 5; i++ {
+		pool.AddWorkItem(ctx, work, nil)
+	}
+	close(gate)
+	pool.Wait()
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(&idle) == 1
+	})
+	for i := 2; i <= 3; i++ {
+		pool.AddWorkItem(ctx, work, nil)
+		pool.Wait()
+		waitFor(t, func() bool {
+			return atomic.LoadInt64(&idle) == int64(i)
+		})
+	}
+
+	// Confirm the function is not called after unsubscribing. The functions for a drop are started together, so once a
+	// function that is still subscribed was called for it, the unsubscribed one would have been too.
+	cancel()
+	cancel()
+	called := make(chan struct{}, 1)
+	pool.OnIdle(func() {
+		called <- struct{}{}
+	})
+	pool.AddWorkItem(ctx, work, nil)
+	pool.Wait()
+	select {
+	case <-called:
+	case <-ctx.Done():
+		t.Error("The function that is still subscribed was not called.")
+		t.FailNow()
+	}
+	if calls := atomic.LoadInt64(&idle); calls != 3 {
+		t.Errorf("Expected the pool to go idle 3 times, but it did %d times.", calls)
+		t.FailNow()
+	}
+}
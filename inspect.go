@@ -0,0 +1,315 @@
+package ctxerrpool
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// MaxPendingItems is the most work items PendingItems returns.
+const MaxPendingItems = 100
+
+// WorkID identifies a work item given to a Pool. It is the order the work item was given to the Pool in, starting at
+// 1, which is also FinishInfo.SubmitSeq. It is returned by AddWorkItem.
+type WorkID uint64
+
+// FlushToken is a barrier for the work items given to a Pool before it was made. See Pool.Checkpoint.
+type FlushToken struct {
+	done chan struct{}
+	err  error
+	last uint64
+}
+
+// Done returns a channel that is closed once the work items have all finished or the Pool has died.
+func (t *FlushToken) Done() <-chan struct{} {
+	return t.done
+}
+
+// Wait blocks until the work items have all finished. If the Pool died first, the reason it died is returned. If the
+// context expires first, the context's error is returned.
+func (t *FlushToken) Wait(ctx context.Context) error {
+	select {
+	case <-t.done:
+		return t.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PendingItem describes a queued work item that has not started yet.
+type PendingItem struct {
+
+	// ID identifies the work item.
+	ID WorkID
+
+	// Data is the data that was given with the work item.
+	Data interface{}
+
+	// Priority is the priority of the work item. See WithPriority.
+	Priority int
+
+	// Submitted is when the work item was given to the Pool.
+	Submitted time.Time
+
+	// Deadline is the deadline of the work item's context. It is the zero time if there is no deadline.
+	Deadline time.Time
+
+	// Remaining is how long is left until the deadline of the work item's context. It is zero if there is no deadline.
+	Remaining time.Duration
+}
+
+// WorkInfo describes a running work item. It can be encoded as JSON, for example for an admin page. See
+// InFlightSnapshot.
+type WorkInfo struct {
+
+	// Category is the category of the work item, if any. See AddWorkItemCategory.
+	Category string `json:"category,omitempty"`
+
+	// Data is the data that was given with the work item. It is not encoded to JSON.
+	Data interface{} `json:"-"`
+
+	// Deadline is the deadline of the work item's context. It is the zero time if there is no deadline.
+	Deadline time.Time `json:"deadline"`
+
+	// Elapsed is how long the work item has been running.
+	Elapsed time.Duration `json:"elapsed"`
+
+	// ID identifies the work item.
+	ID WorkID `json:"id"`
+
+	// Labels are the labels of the work item, if any. They must not be changed. See AddWorkItemLabeled.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Lane is the serial lane of the work item, if any. See AddSerial.
+	Lane string `json:"lane,omitempty"`
+
+	// Remaining is how long is left until the deadline of the work item's context. It is zero if there is no deadline.
+	Remaining time.Duration `json:"remaining"`
+
+	// Started is when a worker started the work item.
+	Started time.Time `json:"started"`
+
+	// Submitted is when the work item was given to the Pool.
+	Submitted time.Time `json:"submitted"`
+
+	// Worker is the number of the worker running the work item, starting at 1.
+	Worker uint `json:"worker"`
+}
+
+// Checkpoint returns a FlushToken for every work item that has been given to the Pool so far. Its Wait returns once all
+// of them have finished, no matter how many work items are given to the Pool afterward. Unlike Flush, it waits for the
+// work items to finish, not just to be taken by a worker. If the Pool is dead, the FlushToken is already resolved with
+// the reason it died.
+func (g *Pool) Checkpoint() *FlushToken {
+	token := &FlushToken{
+		done: make(chan struct{}),
+	}
+	g.queue.mux.Lock()
+	defer g.queue.mux.Unlock()
+	if dead(g.queue.death) {
+		token.err = g.killCause()
+		close(token.done)
+		return token
+	}
+	g.queue.checkpoint(token)
+	return token
+}
+
+// Flush blocks until every work item that was queued when it was called has been taken by a worker or removed from the
+// queue, or until the context expires, in which case the context's error is returned. It does not wait for the work
+// items to finish, see Wait for that. Work items given to the Pool after Flush is called, including ones that were
+// still waiting for room in the queue, are not waited for, so Flush returns even if new work items keep arriving.
+func (g *Pool) Flush(ctx context.Context) error {
+	g.queue.mux.Lock()
+	last := g.queue.seq
+	for {
+
+		// Check for work items that were queued before the call.
+		flushed := true
+		for _, item := range g.queue.items {
+			if item.seq <= last {
+				flushed = false
+				break
+			}
+		}
+		if flushed {
+			g.queue.mux.Unlock()
+			return nil
+		}
+
+		// Wait for the queue to change.
+		changed := g.queue.changed
+		g.queue.mux.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+		g.queue.mux.Lock()
+	}
+}
+
+// Full determines if a work item given to the Pool right now would have to wait for room or be handled by the
+// RejectionPolicy. It is advisory, since the answer may change before the caller acts on it.
+func (g *Pool) Full() bool {
+	_, _, full := g.queue.load()
+	return full
+}
+
+// InFlight returns the number of work items that are running.
+func (g *Pool) InFlight() int {
+	g.queue.mux.Lock()
+	defer g.queue.mux.Unlock()
+	return len(g.queue.running)
+}
+
+// InFlightSnapshot describes the work items that are running, ordered by their IDs. The work items are copied under
+// the queue's lock, but the times are worked out after it is released, so taking a snapshot holds up workers as little
+// as possible. See WorkInfo.
+func (g *Pool) InFlightSnapshot() []WorkInfo {
+
+	// Copy what is needed from the running work items.
+	g.queue.mux.Lock()
+	infos := make([]WorkInfo, 0, len(g.queue.running))
+	for item := range g.queue.running {
+		info := WorkInfo{
+			Category:  item.category,
+			Data:      item.data,
+			ID:        WorkID(item.seq),
+			Labels:    item.labels,
+			Lane:      item.lane,
+			Started:   item.started,
+			Submitted: item.submitted,
+			Worker:    item.worker,
+		}
+		info.Deadline, _ = item.ctx.Deadline()
+		infos = append(infos, info)
+	}
+	g.queue.mux.Unlock()
+
+	// Work out how long each work item has run and has left.
+	now := g.cfg.clock.Now()
+	for i := range infos {
+		infos[i].Elapsed = now.Sub(infos[i].Started)
+		if !infos[i].Deadline.IsZero() {
+			infos[i].Remaining = infos[i].Deadline.Sub(now)
+		}
+	}
+	slices.SortFunc(infos, func(a, b WorkInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return infos
+}
+
+// LaneDepths returns how many work items each serial lane has that have not finished, including the running one. Lanes
+// without work items are not included. See AddSerial.
+func (g *Pool) LaneDepths() map[string]int {
+	g.queue.mux.Lock()
+	defer g.queue.mux.Unlock()
+	depths := make(map[string]int, len(g.queue.lanes))
+	for name, l := range g.queue.lanes {
+		depths[name] = int(l.depth)
+	}
+	return depths
+}
+
+// LoadFactor returns the number of running and queued work items divided by the number of workers plus the size of the
+// queue. It is near 1 when the Pool is saturated and can go above 1 when the overflow buffer is used. It is advisory,
+// since the answer may change before the caller acts on it. Producers can use it to shed low-value work before
+// submitting it.
+func (g *Pool) LoadFactor() float64 {
+	active, capacity, _ := g.queue.load()
+	if capacity == 0 {
+		return 1
+	}
+	return float64(active) / float64(capacity)
+}
+
+// Pending returns the number of queued work items that have not been taken by a worker yet. Work items still waiting
+// for room in the queue are not included.
+func (g *Pool) Pending() int {
+	g.queue.mux.Lock()
+	defer g.queue.mux.Unlock()
+	return len(g.queue.items)
+}
+
+// PendingCount returns the number of work items that were accepted but have not finished, whether they are waiting for
+// room in the queue, queued, or running. It is zero exactly when Wait would return, so it can tell if a shutdown has
+// anything to wait for. Rejected work items are only counted until they are reported.
+func (g *Pool) PendingCount() int64 {
+	return atomic.LoadInt64(&g.pending)
+}
+
+// PendingItems returns the queued work items that have not been taken by a worker yet, in queue order: highest priority
+// first, then as set by WithDispatchOrder. At most MaxPendingItems are returned. Work items still waiting for room in
+// the queue are not included. The snapshot is taken under the queue's lock, so it is safe to call while workers take
+// work items.
+//
+// Workers mostly take work items in queue order, but skip the ones held back by WithCategoryLimit or a busy serial
+// lane, and with WithDeterministicDispatch, the ones assigned to other workers. With WithFIFOGuarantee, or in a Pool
+// with 1 worker and a queue, the work item given to the Pool first is taken next instead.
+func (g *Pool) PendingItems() []PendingItem {
+	now := g.cfg.clock.Now()
+
+	g.queue.mux.Lock()
+	defer g.queue.mux.Unlock()
+
+	// Describe the work items at the front of the queue.
+	length := len(g.queue.items)
+	if length > MaxPendingItems {
+		length = MaxPendingItems
+	}
+	pending := make([]PendingItem, length)
+	for i, item := range g.queue.items[:length] {
+		pending[i] = PendingItem{
+			ID:        WorkID(item.seq),
+			Data:      item.data,
+			Priority:  item.priority,
+			Submitted: item.submitted,
+		}
+		if deadline, ok := item.ctx.Deadline(); ok {
+			pending[i].Deadline = deadline
+			pending[i].Remaining = deadline.Sub(now)
+		}
+	}
+
+	return pending
+}
+
+// RunningHandlers returns how many error handlers are running, including those given to single work items. See
+// WithMaxErrorConcurrency.
+func (g *Pool) RunningHandlers() int64 {
+	return atomic.LoadInt64(&g.handlers)
+}
+
+// String implements the fmt.Stringer interface with a summary of the Pool's state for log lines, such as
+// "ctxerrpool(name=fetcher workers=8 busy=3 queued=12 completed=4210 failed=7 state=running)". The name is only given
+// if the Pool was registered. The state is running, draining while Kill with WithDrainOnKill or KillWithPolicy waits
+// for work items, or dead. It only reads counters, so it is cheap and never waits for work items.
+func (g *Pool) String() string {
+	stats := g.Stats()
+	var failed uint64
+	for i := range g.workerStats {
+		failed += atomic.LoadUint64(&g.workerStats[i].errored)
+	}
+	g.queue.mux.Lock()
+	closed := g.queue.closed
+	g.queue.mux.Unlock()
+	state := "running"
+	switch {
+	case g.Dead():
+		state = "dead"
+	case closed:
+		state = "draining"
+	}
+	var name string
+	if registered := g.name.Load(); registered != nil {
+		name = "name=" + *registered + " "
+	}
+	return fmt.Sprintf("ctxerrpool(%sworkers=%d busy=%d queued=%d completed=%d failed=%d state=%s)", name,
+		len(g.workerStats), stats.Running, stats.Queued, g.CompletedTotal(), failed, state)
+}
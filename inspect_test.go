@@ -0,0 +1,433 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestCheckpoint confirms that a FlushToken waits for the work items given to the Pool before it, but not after, and is
+// resolved with an error when the Pool dies.
+func TestCheckpoint(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Confirm a checkpoint of an idle pool is already resolved.
+	if err := pool.Checkpoint().Wait(ctx); err != nil {
+		t.Errorf("Expected the checkpoint of an idle pool to be resolved. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Give the pool a work item before the checkpoint and one after it, both running until told to stop.
+	work := func(workCtx context.Context, data interface{}) error {
+		<-data.(chan struct{})
+		return nil
+	}
+	before, after := make(chan struct{}), make(chan struct{})
+	pool.AddWorkItem(ctx, work, before)
+	token := pool.Checkpoint()
+	pool.AddWorkItem(ctx, work, after)
+
+	// Confirm the checkpoint waits for the work item before it, but not the one after it.
+	select {
+	case <-token.Done():
+		t.Error("The checkpoint was resolved before the work item before it finished.")
+		t.FailNow()
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(before)
+	if err := token.Wait(ctx); err != nil {
+		t.Errorf("Expected the checkpoint to be resolved. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm a checkpoint that is waiting when the pool dies is resolved with the reason it died.
+	token = pool.Checkpoint()
+	pool.Kill()
+	close(after)
+	if err := token.Wait(ctx); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected the checkpoint to be resolved with ErrPoolKilled. Error: %v", err)
+		t.FailNow()
+	}
+	if err := pool.Checkpoint().Wait(ctx); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected the checkpoint of a dead pool to be resolved with ErrPoolKilled. Error: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestCheckpointKill confirms that a checkpoint that is waiting when the pool dies is always resolved with the reason
+// it died, even though killing the pool makes the work items it waits for finish.
+func TestCheckpointKill(t *testing.T) {
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Kill many pools with a checkpoint waiting for a running work item.
+	for i := 0; i < 100; i++ {
+
+		// Create a worker pool with 1 worker and give it a work item that runs until the pool dies.
+		pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+		started := make(chan struct{})
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			close(started)
+			<-workCtx.Done()
+			return nil
+		}, nil)
+		<-started
+
+		// Confirm the checkpoint is resolved with the reason the pool died.
+		token := pool.Checkpoint()
+		pool.Kill()
+		if err := token.Wait(ctx); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+			t.Errorf("Expected the checkpoint to be resolved with ErrPoolKilled on attempt %d. Error: %v", i, err)
+			t.FailNow()
+		}
+	}
+}
+
+// TestFlush confirms that Flush waits for the queued work items to be taken by a worker, but not for them to finish.
+func TestFlush(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a queue.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed, then queue a work item that waits for the second gate.
+	first := make(chan struct{})
+	second := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-first
+		return nil
+	}, "first")
+	<-started
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		<-second
+		return nil
+	}, "second")
+
+	// Confirm Flush waits while the work item is queued.
+	short, cancelShort := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancelShort()
+	if err := pool.Flush(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Flush to time out. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Let the queued work item be taken. Flush should return while it is still running.
+	close(first)
+	if err := pool.Flush(ctx); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	close(second)
+	pool.Wait()
+}
+
+// TestInFlightSnapshot confirms that the running work items are described in order and can be encoded as JSON.
+func TestInFlightSnapshot(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep both workers busy until the gate is closed.
+	gate := make(chan struct{})
+	work := func(workCtx context.Context, data interface{}) error {
+		<-gate
+		return nil
+	}
+	pool.AddWorkItemLabeled(ctx, work, nil, map[string]string{"tenant": "a"})
+	pool.AddWorkItem(context.Background(), work, nil)
+	waitFor(t, func() bool {
+		return pool.InFlight() == 2
+	})
+
+	// Confirm the running work items are described.
+	infos := pool.InFlightSnapshot()
+	if len(infos) != 2 || infos[0].ID != 1 || infos[1].ID != 2 {
+		t.Errorf("Expected the 2 running work items in order. Snapshot: %+v", infos)
+		t.FailNow()
+	}
+	if infos[0].Labels["tenant"] != "a" || infos[0].Remaining <= 0 || infos[0].Started.IsZero() ||
+		infos[0].Elapsed < 0 || infos[0].Worker == 0 {
+		t.Errorf("Unexpected description of the first work item: %+v.", infos[0])
+		t.FailNow()
+	}
+	if !infos[1].Deadline.IsZero() || infos[1].Remaining != 0 {
+		t.Errorf("Expected the second work item to have no deadline: %+v.", infos[1])
+		t.FailNow()
+	}
+
+	// Confirm the snapshot can be encoded as JSON.
+	encoded, err := json.Marshal(infos)
+	if err != nil || !strings.Contains(string(encoded), `"tenant":"a"`) {
+		t.Errorf("The snapshot was not encoded. Encoded: %s. Error: %v", encoded, err)
+		t.FailNow()
+	}
+
+	// Let the work items finish.
+	close(gate)
+	pool.Wait()
+	if infos = pool.InFlightSnapshot(); len(infos) != 0 {
+		t.Errorf("Expected no running work items. Snapshot: %+v", infos)
+		t.FailNow()
+	}
+}
+
+// TestIntrospectionFromHandler confirms that the introspection methods can be called from the error handler while the
+// pool is busy. Run it with -race.
+func TestIntrospectionFromHandler(t *testing.T) {
+
+	// Create a wait pool that waits for the errors to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(20)
+
+	// Create a worker pool with 2 workers and a queue that inspects itself from the error handler.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+		_ = pool.Stats()
+		_ = pool.InFlight()
+		_ = pool.Pending()
+		_ = pool.PendingItems()
+		_ = pool.LoadFactor()
+		_ = pool.Full()
+	}, ctxerrpool.WithQueueSize(4))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool work that fails.
+	for i := 0; i < 20; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return errors.New("test")
+		}, i)
+	}
+
+	// Wait for the worker pool and errors.
+	pool.Wait()
+	wg.Wait()
+}
+
+// TestLoadFactor confirms that LoadFactor and Full describe a saturated pool.
+func TestLoadFactor(t *testing.T) {
+
+	// Create a worker pool with 2 workers and a queue.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(2))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Confirm an idle pool has no load.
+	if pool.LoadFactor() != 0 || pool.Full() {
+		t.Errorf("Expected no load. Load factor: %v.", pool.LoadFactor())
+		t.FailNow()
+	}
+
+	// Keep both workers busy until the gate is closed, then fill the queue.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			started <- struct{}{}
+			<-gate
+			return nil
+		}, i)
+		if i < 2 {
+			<-started
+		}
+	}
+
+	// Confirm the pool is saturated.
+	if pool.LoadFactor() != 1 || !pool.Full() || pool.InFlight() != 2 || pool.Pending() != 2 {
+		t.Errorf("Expected the pool to be saturated. Load factor: %v.", pool.LoadFactor())
+		t.FailNow()
+	}
+
+	// Let the work finish.
+	go func() {
+		for range started {
+		}
+	}()
+	close(gate)
+	pool.Wait()
+	close(started)
+}
+
+// TestPendingCount confirms that PendingCount follows work items that are waiting for room, queued, and running, does
+// not count refused work items, and is zero once Wait returns.
+func TestPendingCount(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 1 queued work item.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Confirm the count after each step.
+	expect := func(step string, count int64) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for pending := pool.PendingCount(); pending != count; pending = pool.PendingCount() {
+			if time.Now().After(deadline) {
+				t.Errorf("Expected %d pending work items after %s, got %d.", count, step, pending)
+				t.FailNow()
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Keep the worker busy until the gate is closed, then fill the queue.
+	gate := make(chan struct{})
+	work := func(workCtx context.Context, data interface{}) error {
+		<-gate
+		return nil
+	}
+	pool.AddWorkItem(ctx, work, nil)
+	expect("a running work item", 1)
+	pool.AddWorkItem(ctx, work, nil)
+	expect("a queued work item", 2)
+
+	// Add a work item that waits for room and one that is refused.
+	waiting, stop := context.WithCancel(ctx)
+	go pool.AddWorkItem(waiting, work, nil)
+	expect("a work item waiting for room", 3)
+	if err := pool.AddWorkItemContext(ctx, work, nil); !errors.Is(err, ctxerrpool.ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull. Error: %v", err)
+		t.FailNow()
+	}
+	expect("a refused work item", 3)
+
+	// Give up waiting, then let the rest finish.
+	stop()
+	expect("giving up waiting", 2)
+	close(gate)
+	pool.Wait()
+	if pending := pool.PendingCount(); pending != 0 {
+		t.Errorf("Expected no pending work items after Wait, got %d.", pending)
+		t.FailNow()
+	}
+}
+
+// TestPendingItems confirms that PendingItems describes the queued work items in order.
+func TestPendingItems(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a queue.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(3))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, "busy")
+	<-started
+
+	// Queue some work items.
+	for i := 0; i < 3; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return nil
+		}, i)
+	}
+
+	// Confirm the queued work items are described in order.
+	pending := pool.PendingItems()
+	if len(pending) != 3 {
+		t.Errorf("Expected 3 pending work items, got %d.", len(pending))
+		t.FailNow()
+	}
+	for i, item := range pending {
+		if item.Data != i || item.ID != ctxerrpool.WorkID(i+2) || item.Remaining <= 0 || item.Submitted.IsZero() {
+			t.Errorf("Unexpected pending work item: %+v.", item)
+			t.FailNow()
+		}
+	}
+
+	// Let the work finish.
+	close(gate)
+	pool.Wait()
+	if pending = pool.PendingItems(); len(pending) != 0 {
+		t.Errorf("Expected no pending work items, got %d.", len(pending))
+		t.FailNow()
+	}
+}
+
+// TestString confirms that String summarizes the state of the Pool.
+func TestString(t *testing.T) {
+
+	// Create a worker pool with 2 workers and register it.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+	if err := ctxerrpool.Register("TestString", pool); err != nil {
+		t.Errorf("Failed to register the pool. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Give the pool a work item that fails and one that keeps a worker busy.
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return errors.New("test")
+	}, nil)
+	pool.Wait()
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, nil)
+	<-started
+
+	// Confirm the summary.
+	expected := "ctxerrpool(name=TestString workers=2 busy=1 queued=0 completed=1 failed=1 state=running)"
+	if summary := pool.String(); summary != expected {
+		t.Errorf("Unexpected summary.\nExpected: %s\nActual: %s", expected, summary)
+		t.FailNow()
+	}
+
+	// Confirm the summary of a dead pool.
+	close(gate)
+	pool.Wait()
+	pool.Kill()
+	if summary := pool.String(); !strings.HasSuffix(summary, "completed=2 failed=1 state=dead)") {
+		t.Errorf("Unexpected summary of a dead pool: %s", summary)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,102 @@
+package ctxerrpool
+
+import (
+	"context"
+	"time"
+)
+
+// KillPolicy describes how a Pool should treat its outstanding work items when it is killed with KillWithPolicy.
+// Regardless of the policy, the Pool stops accepting work items right away and work items that were submitted, but
+// never started, are reported as dropped.
+type KillPolicy struct {
+
+	// CancelQueued drops the queued work items right away. Otherwise, queued work items keep being started while the
+	// Pool waits for work to finish.
+	CancelQueued bool
+
+	// CancelInFlight cancels the context of the running work items right away, and of the queued work items as soon as
+	// they are started. Otherwise, they are given up to WaitInFlight to finish before their contexts are canceled.
+	CancelInFlight bool
+
+	// WaitInFlight is how long to wait for the work items that were not canceled to finish before the Pool dies. Zero
+	// means there is no limit. It does not apply when both queued and running work items are canceled.
+	WaitInFlight time.Duration
+}
+
+// KillAndWait kills the Pool and then waits for the goroutines running Work functions to return, or for the given
+// context to expire. The context's error is returned if it expired first.
+//
+// This is different from calling Kill and then Wait. Wait returns as soon as the Pool is dead, even though Work
+// functions that were running may not have noticed their context was canceled yet. KillAndWait waits for them to
+// actually return. Queued work items are not run, see KillWithPolicy to let them finish first. With WithDrainOnKill,
+// the queued work items are run first.
+func (g *Pool) KillAndWait(ctx context.Context) error {
+	g.Kill()
+
+	// Make a channel to wait for the Pool to die and the Work functions to return.
+	done := make(chan struct{})
+	g.spawn(func() {
+		<-g.death
+		g.working.Wait()
+		close(done)
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+// KillWithPolicy kills the Pool according to the given policy. It returns once the Pool has died. A KillPolicy that
+// cancels both queued and running work items behaves the same as Kill without WithDrainOnKill.
+func (g *Pool) KillWithPolicy(policy KillPolicy) {
+
+	// Kill the pool right away if nothing is waited for.
+	if policy.CancelQueued && policy.CancelInFlight {
+		g.kill(ErrPoolKilled)
+		return
+	}
+
+	// Stop accepting work items.
+	g.queue.close()
+
+	// Drop the queued work items, if configured to.
+	if policy.CancelQueued {
+		for _, item := range g.queue.drain() {
+			g.drop(item)
+		}
+	}
+
+	// Cancel the running work items and the ones started from now on, if configured to.
+	if policy.CancelInFlight {
+		for _, item := range g.queue.abortInFlight() {
+			item.fail(ErrPoolKilled)
+			item.abort(ErrPoolKilled)
+		}
+	}
+
+	// Wait for the work to be done, for up to the configured duration.
+	var timeout <-chan time.Time
+	if policy.WaitInFlight > 0 {
+		timer := g.cfg.clock.NewTimer(policy.WaitInFlight)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	for {
+		drained, changed := g.queue.drained()
+		if drained {
+			break
+		}
+		select {
+		case <-changed:
+			continue
+		case <-g.death:
+		case <-timeout:
+		}
+		break
+	}
+
+	g.kill(ErrPoolKilled)
+}
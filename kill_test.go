@@ -0,0 +1,398 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// TestKillAndWait confirms that KillAndWait waits for running Work functions to return.
+func TestKillAndWait(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Start work that takes a moment to unwind after its context is canceled.
+	var returned int64
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		time.Sleep(time.Millisecond * 20)
+		atomic.StoreInt64(&returned, 1)
+		return workCtx.Err()
+	}, "unwind")
+	<-started
+
+	// Kill the pool and wait for the work to return.
+	if err := pool.KillAndWait(ctx); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the work returned.
+	if atomic.LoadInt64(&returned) != 1 {
+		t.Error("KillAndWait returned before the work did.")
+		t.FailNow()
+	}
+}
+
+// TestKillCause confirms that the cause given to KillCause is recorded and still matches ErrPoolKilled.
+func TestKillCause(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+
+	// Kill the pool with a cause, then with another one.
+	cause := errors.New("test")
+	pool.KillCause(cause)
+	pool.KillCause(errors.New("ignored"))
+
+	// Confirm the first cause was kept.
+	if err := pool.AsContext().Err(); !errors.Is(err, cause) || !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Unexpected cause: %v.", err)
+		t.FailNow()
+	}
+}
+
+// TestKillWithPolicy confirms that each combination of a KillPolicy treats queued and running work items as expected.
+func TestKillWithPolicy(t *testing.T) {
+
+	// Create the test cases.
+	testCases := []struct {
+		name           string
+		policy         ctxerrpool.KillPolicy
+		runningFor     time.Duration
+		expectCanceled bool
+		expectQueued   int64
+	}{
+		{
+			name:           "Same as Kill",
+			policy:         ctxerrpool.KillPolicy{CancelQueued: true, CancelInFlight: true},
+			runningFor:     time.Second,
+			expectCanceled: true,
+		},
+		{
+			name:       "Cancel queued and let running finish",
+			policy:     ctxerrpool.KillPolicy{CancelQueued: true, WaitInFlight: time.Second},
+			runningFor: time.Millisecond * 10,
+		},
+		{
+			name:           "Cancel queued and cut running short",
+			policy:         ctxerrpool.KillPolicy{CancelQueued: true, WaitInFlight: time.Millisecond * 10},
+			runningFor:     time.Second,
+			expectCanceled: true,
+		},
+		{
+			name:         "Let everything finish",
+			policy:       ctxerrpool.KillPolicy{},
+			runningFor:   time.Millisecond * 10,
+			expectQueued: 2,
+		},
+		{
+			name:           "Cancel running and let queued finish",
+			policy:         ctxerrpool.KillPolicy{CancelInFlight: true},
+			runningFor:     time.Second,
+			expectCanceled: true,
+			expectQueued:   2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			// Create a worker pool with 1 worker and a queue.
+			pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(2))
+
+			// Create a context.
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			// Keep the worker busy for the configured duration or until its context is canceled.
+			started := make(chan struct{})
+			returned := make(chan bool)
+			pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+				close(started)
+				select {
+				case <-workCtx.Done():
+					returned <- true
+				case <-time.After(testCase.runningFor):
+					returned <- false
+				}
+				return nil
+			}, "running")
+			<-started
+
+			// Queue work items that count how many of them ran.
+			var queued int64
+			for i := 0; i < 2; i++ {
+				pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+					atomic.AddInt64(&queued, 1)
+					return nil
+				}, i)
+			}
+
+			// Kill the pool with the policy.
+			go pool.KillWithPolicy(testCase.policy)
+
+			// Confirm the running work item was treated as expected.
+			if canceled := <-returned; canceled != testCase.expectCanceled {
+				t.Errorf("Expected the running work item to be canceled: %t, but it was: %t.", testCase.expectCanceled,
+					canceled)
+				t.FailNow()
+			}
+
+			// Confirm the queued work items were treated as expected once their Work functions returned. Work items
+			// started canceled may still be running when the pool dies.
+			<-pool.Death()
+			ctxerrpooltest.AssertNoLeaks(t, pool)
+			if ran := atomic.LoadInt64(&queued); ran != testCase.expectQueued {
+				t.Errorf("Expected %d queued work items to run, but %d did.", testCase.expectQueued, ran)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+// TestKillWithPolicyCancelInFlight confirms that a running work item canceled by KillWithPolicy reports that the Pool
+// was killed, both to its Work function and to the error handler.
+func TestKillWithPolicyCancelInFlight(t *testing.T) {
+
+	// Create a worker pool with 1 worker that records the errors it handles.
+	errs := make(chan error, 1)
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		errs <- err
+	})
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until its context is canceled, record why, and return once the error was handled.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	causes := make(chan error, 1)
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		causes <- context.Cause(workCtx)
+		<-gate
+		return nil
+	}, nil)
+	<-started
+
+	// Cancel the running work item and confirm both its context and the error handler tell why.
+	killed := make(chan struct{})
+	go func() {
+		pool.KillWithPolicy(ctxerrpool.KillPolicy{CancelInFlight: true})
+		close(killed)
+	}()
+	defer func() {
+		close(gate)
+		<-killed
+	}()
+	for _, name := range []string{"cause", "error"} {
+		c := causes
+		if name == "error" {
+			c = errs
+		}
+		select {
+		case err := <-c:
+			if !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+				t.Errorf("Expected the %s to be ErrPoolKilled. Error: %v", name, err)
+				t.FailNow()
+			}
+		case <-ctx.Done():
+			t.Errorf("The %s was never given.", name)
+			t.FailNow()
+		}
+	}
+}
+
+// TestKillWithPolicyWaitInFlight confirms that KillWithPolicy cancels work items started after it canceled the running
+// ones, gives up on Work functions that ignore their context once WaitInFlight has passed, and leaves nothing behind.
+func TestKillWithPolicyWaitInFlight(t *testing.T) {
+
+	// Create a worker pool with 1 worker, a queue, and a clock controlled by the test.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithClock(clock),
+		ctxerrpool.WithQueueSize(1))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy with a Work function that ignores its context until the first gate is closed, then queue one
+	// that records its context and ignores it until the second gate is closed.
+	first := make(chan struct{})
+	second := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-first
+		return nil
+	}, "running")
+	<-started
+	causes := make(chan error, 1)
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		causes <- context.Cause(workCtx)
+		<-second
+		return nil
+	}, "queued")
+
+	// Cancel the running work item and wait for up to an hour for the work to be done.
+	killed := make(chan struct{})
+	go func() {
+		pool.KillWithPolicy(ctxerrpool.KillPolicy{CancelInFlight: true, WaitInFlight: time.Hour})
+		close(killed)
+	}()
+	clock.BlockUntil(1)
+
+	// Let the running work item return and confirm the queued one was canceled as it started.
+	close(first)
+	select {
+	case err := <-causes:
+		if !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+			t.Errorf("Expected the queued work item to start canceled. Cause: %v", err)
+			t.FailNow()
+		}
+	case <-ctx.Done():
+		t.Error("The queued work item never started.")
+		t.FailNow()
+	}
+
+	// Let the hour pass and confirm the pool died while the queued work item still ignores its context.
+	clock.Advance(time.Hour)
+	select {
+	case <-killed:
+	case <-ctx.Done():
+		t.Error("KillWithPolicy did not return once WaitInFlight passed.")
+		t.FailNow()
+	}
+
+	// Let the queued work item return and confirm nothing was left behind.
+	close(second)
+	ctxerrpooltest.AssertNoLeaks(t, pool)
+}
+
+// TestWithDrainOnKill confirms that Kill lets queued work items run when the pool drains on kill and that work items
+// given to the pool after Kill are dropped.
+func TestWithDrainOnKill(t *testing.T) {
+
+	// Count the work items that ran and the ones that were dropped.
+	var ran, dropped int64
+
+	// Create a worker pool with 1 worker, a queue, and draining on kill.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		if !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+		atomic.AddInt64(&dropped, 1)
+	}, ctxerrpool.WithDrainOnKill(), ctxerrpool.WithQueueSize(3))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed, then queue some work items.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		atomic.AddInt64(&ran, 1)
+		return nil
+	}, "busy")
+	<-started
+	for i := 0; i < 3; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			atomic.AddInt64(&ran, 1)
+			return nil
+		}, i)
+	}
+
+	// Kill the pool, then confirm it no longer accepts work items.
+	pool.Kill()
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		t.Error("A work item given to the pool after Kill ran.")
+		t.FailNow()
+		return nil
+	}, "late")
+
+	// Let the work finish and confirm the queued work items ran before the pool died.
+	close(gate)
+	if err := pool.KillAndWait(ctx); err != nil {
+		t.Errorf("The pool did not die. Error: %v", err)
+		t.FailNow()
+	}
+	if ran := atomic.LoadInt64(&ran); ran != 4 {
+		t.Errorf("Expected 4 work items to run, got %d.", ran)
+		t.FailNow()
+	}
+	for atomic.LoadInt64(&dropped) != 1 {
+		select {
+		case <-ctx.Done():
+			t.Error("The work item given to the pool after Kill was not dropped.")
+			t.FailNow()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// TestWithMaxLifetime confirms that the pool kills itself with ErrMaxLifetime when its lifetime is over.
+func TestWithMaxLifetime(t *testing.T) {
+
+	// Create a worker pool with 1 worker, a maximum lifetime, and a clock controlled by the test.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithClock(clock),
+		ctxerrpool.WithMaxLifetime(time.Hour))
+
+	// Confirm the deadline.
+	if deadline, ok := pool.Deadline(); !ok || !deadline.Equal(time.Time{}.Add(time.Hour)) {
+		t.Errorf("Unexpected deadline: %v.", deadline)
+		t.FailNow()
+	}
+
+	// Let the lifetime pass and confirm the pool died because of it.
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	ctx := pool.AsContext()
+	<-ctx.Done()
+	if err := ctx.Err(); !errors.Is(err, ctxerrpool.ErrMaxLifetime) || !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Unexpected cause: %v.", err)
+		t.FailNow()
+	}
+}
+
+// TestWithMaxLifetimeKilled confirms that the lifetime timer is stopped when the pool is killed first.
+func TestWithMaxLifetimeKilled(t *testing.T) {
+
+	// Create a worker pool with 1 worker, a maximum lifetime, and a clock controlled by the test.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithClock(clock),
+		ctxerrpool.WithMaxLifetime(time.Hour))
+
+	// Kill the pool and confirm the timer is stopped.
+	clock.BlockUntil(1)
+	pool.Kill()
+	waitFor(t, func() bool {
+		return clock.Timers() == 0
+	})
+
+	// Confirm a late fire would not change why the pool died.
+	clock.Advance(time.Hour)
+	if err := pool.AsContext().Err(); errors.Is(err, ctxerrpool.ErrMaxLifetime) {
+		t.Errorf("Unexpected cause: %v.", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,92 @@
+package ctxerrpool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MapStreamOrdered gives every input to the Pool under the given context and delivers the results on the returned
+// channel in the order of the inputs. Results are delivered as soon as every result before them has been, so a slow
+// work item only holds back the results after it, not the whole batch. The channel is closed after the last result.
+// Each Result's Data is its input. Inputs that could not run because the Pool is dead have an error matching
+// ErrPoolKilled. Errors are still reported to the error handler as usual.
+//
+// Results that finish out of order are kept in a reorder buffer until the gap before them is filled, so its size is
+// bounded by the largest number of results finished behind an unfinished one. The channel is buffered for every input,
+// so a slow reader never holds up the workers, at the cost of holding the results it has not read yet.
+//
+// MapStreamOrdered returns right away. The inputs are given to the Pool in order from another goroutine, which blocks
+// like AddWorkItem does. It panics with ErrNilWork if fn is nil.
+func MapStreamOrdered[In, Out any](ctx context.Context, pool *Pool, inputs []In,
+	fn func(ctx context.Context, in In) (Out, error)) <-chan Result[Out] {
+	if fn == nil {
+		panic(ErrNilWork)
+	}
+	results := make(chan Result[Out], len(inputs))
+	if len(inputs) == 0 {
+		close(results)
+		return results
+	}
+
+	// Keep the values, timings, and out-of-order results and a mutex for them.
+	mux := &sync.Mutex{}
+	buffer := make(map[int]Result[Out])
+	durations := make([]time.Duration, len(inputs))
+	values := make([]Out, len(inputs))
+	next := 0
+
+	// Give every input to the pool and deliver the results in order as they finish.
+	submit := func(i int, in In) {
+		item := &workItem{
+			work: func(workCtx context.Context, data interface{}) error {
+				started := pool.cfg.clock.Now()
+				value, err := fn(workCtx, in)
+				mux.Lock()
+				defer mux.Unlock()
+				durations[i] = pool.cfg.clock.Now().Sub(started)
+				if err == nil {
+					values[i] = value
+				}
+				return err
+			},
+			data: in,
+		}
+		item.onFinish = func(err error) {
+			mux.Lock()
+			defer mux.Unlock()
+			result := Result[Out]{
+				Data:     in,
+				Duration: durations[i],
+				Err:      err,
+				ID:       WorkID(item.seq),
+			}
+			if err == nil {
+				result.Value = values[i]
+			}
+			buffer[i] = result
+
+			// Deliver every result that is no longer waiting on an earlier one.
+			for {
+				result, ok := buffer[next]
+				if !ok {
+					break
+				}
+				delete(buffer, next)
+				results <- result
+				next++
+			}
+			if next == len(inputs) {
+				close(results)
+			}
+		}
+		pool.addWorkItem(ctx, item)
+	}
+	pool.spawn(func() {
+		for i, in := range inputs {
+			submit(i, in)
+		}
+	})
+
+	return results
+}
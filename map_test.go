@@ -0,0 +1,73 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestMapStreamOrdered confirms that results are delivered in the order of the inputs and that results before a slow
+// work item are delivered without waiting for it.
+func TestMapStreamOrdered(t *testing.T) {
+
+	// Create an error for odd inputs.
+	errOdd := errors.New("odd")
+
+	// Create a worker pool with 4 workers.
+	pool := ctxerrpool.New(4, func(pool *ctxerrpool.Pool, err error) {
+
+		// This test case should only have the error for odd inputs.
+		if !errors.Is(err, errOdd) {
+			t.Errorf("An error occurred. Error: %v", err)
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Double every input, but hold the input 2 until the gate is closed.
+	gate := make(chan struct{})
+	double := func(ctx context.Context, in int) (int, error) {
+		if in == 2 {
+			<-gate
+		}
+		if in%2 == 1 {
+			return 0, errOdd
+		}
+		return in * 2, nil
+	}
+	results := ctxerrpool.MapStreamOrdered(ctx, pool, []int{0, 1, 2, 3, 4}, double)
+
+	// Confirm the results before the slow work item are delivered while it is still running.
+	for i := 0; i < 2; i++ {
+		result := <-results
+		if result.Data != i {
+			t.Errorf("Expected the result for input %d, got %v.", i, result.Data)
+			t.FailNow()
+		}
+	}
+	close(gate)
+
+	// Confirm the rest of the results are delivered in order with their values and errors.
+	i := 2
+	for result := range results {
+		if result.Data != i || result.ID == 0 {
+			t.Errorf("Expected the result for input %d, got %v with ID %d.", i, result.Data, result.ID)
+			t.FailNow()
+		}
+		if i%2 == 1 && !errors.Is(result.Err, errOdd) || i%2 == 0 && (result.Err != nil || result.Value != i*2) {
+			t.Errorf("The result for input %d is wrong.\nValue: %d\nError: %v", i, result.Value, result.Err)
+			t.FailNow()
+		}
+		i++
+	}
+	if i != 5 {
+		t.Errorf("Expected 5 results, got %d.", i)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,488 @@
+package ctxerrpool
+
+import (
+	"context"
+	"time"
+)
+
+// RejectionPolicy determines what happens to a work item when the Pool has no room for it.
+type RejectionPolicy uint8
+
+const (
+
+	// Block makes AddWorkItem wait until there is room for the work item or its context expires. This is the default.
+	Block RejectionPolicy = iota
+
+	// Reject makes AddWorkItem give up right away and report ErrQueueFull to the error handler.
+	Reject
+
+	// DropOldest makes AddWorkItem remove the oldest queued work item to make room. The removed work item is reported
+	// to the error handler with ErrDroppedOldest. If nothing is queued, there is nothing to remove and AddWorkItem
+	// waits as it would with Block.
+	DropOldest
+)
+
+// PanicPolicy determines what happens when a Work function panics.
+type PanicPolicy uint8
+
+const (
+
+	// Recover recovers the panic and reports a PanicError with the stack trace to the error handler, or gives it to the
+	// function set with WithPanicHandler, if any. This is the default.
+	Recover PanicPolicy = iota
+
+	// Repanic re-raises the panic on a new goroutine, which crashes the process. Use it in development so panics are
+	// not missed.
+	Repanic
+
+	// Handler recovers the panic and gives it to the function set with WithPanicFunc instead of the error handler. If
+	// no function is set, it behaves like Recover.
+	Handler
+)
+
+// DispatchOrder determines which of the queued work items with the same priority a worker takes first.
+type DispatchOrder uint8
+
+const (
+
+	// FIFO makes workers take the work item that was queued first. It is fair to the producers and suits serving
+	// requests. This is the default. It is not related to WithFIFO, which orders producers waiting for room.
+	FIFO DispatchOrder = iota
+
+	// LIFO makes workers take the work item that was queued last. Recursive workloads, such as crawlers and tree
+	// traversals, then go depth first, which keeps the number of queued work items and their memory small.
+	LIFO
+)
+
+// FinishInfo describes a work item that finished. It is given to the function set with WithOnFinish.
+type FinishInfo struct {
+
+	// CompleteSeq is the order the work item finished in, starting at 1. Comparing it to SubmitSeq shows how work items
+	// were reordered. With multiple workers, the order work items are given to the Pool is not the order they run or
+	// finish in.
+	CompleteSeq uint64
+
+	// Data is the data that was given with the work item.
+	Data interface{}
+
+	// Duration is how long the Work function ran. It is zero if the work item never started.
+	Duration time.Duration
+
+	// Err is the error that ended the work item, if any. It is nil if the work item succeeded.
+	Err error
+
+	// Labels are the labels that were given with the work item, if any. They must not be modified.
+	Labels map[string]string
+
+	// SubmitSeq is the order the work item was given to the Pool in, starting at 1.
+	SubmitSeq uint64
+
+	// Worker is the number of the worker that took the work item, starting at 1. It is zero if no worker took it.
+	Worker uint
+}
+
+// ContextFactory creates the context for a work item and a function to release its resources.
+type ContextFactory func() (ctx context.Context, cancel context.CancelFunc)
+
+// PanicFunc handles a panic in a Work function. It is given the work item's data, the value given to panic, and the
+// stack trace of the goroutine that panicked.
+type PanicFunc func(data interface{}, recovered interface{}, stack []byte)
+
+// Option changes the configuration of a Pool when it is created with New.
+type Option func(cfg *config)
+
+// config holds the configuration of a Pool.
+type config struct {
+	baseValues        func(ctx context.Context) context.Context
+	breakerCooldown   time.Duration
+	breakerThreshold  uint
+	breakerWindow     time.Duration
+	categoryLimits    map[string]uint
+	clock             Clock
+	contextFactory    ContextFactory
+	deadlockDetection bool
+	deterministic     bool
+	dispatchOrder     DispatchOrder
+	drainOnKill       bool
+	fifo              bool
+	fifoGuarantee     bool
+	maxErrorHandlers  uint
+	maxLifetime       time.Duration
+	maxQueuedBytes    int64
+	maxRequeues       uint
+	onBreakerChange   func(state BreakerState)
+	onFinish          func(info FinishInfo)
+	onRecycle         func(worker uint, processed uint64, age time.Duration)
+	overflow          uint
+	panicFunc         PanicFunc
+	panicHandler      PanicHandler
+	panicPolicy       PanicPolicy
+	queueSize         uint
+	rejection         RejectionPolicy
+	retryBudget       float64
+	retryTokens       float64
+	reportNilWork     bool
+	seed              int64
+	slowLog           func(data interface{}, duration time.Duration)
+	slowThreshold     time.Duration
+	spillLimit        uint
+	watchdog          time.Duration
+	workerInit        func(worker uint) error
+	workerMaxAge      time.Duration
+	workerMaxItems    uint64
+	workerTeardown    func(worker uint)
+}
+
+// newConfig creates the configuration for a Pool from the given options.
+func newConfig(options []Option) config {
+	cfg := config{
+		clock:       realClock{},
+		maxRequeues: DefaultMaxRequeues,
+		contextFactory: func() (context.Context, context.CancelFunc) {
+			return context.WithCancel(context.Background())
+		},
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// WithBaseContextValues sets a function that adds values to a context, such as a service name or a logger, that every
+// Work function should be able to find in its context. It is called once by New with context.Background and only the
+// values of the context it returns are used, so a deadline or cancellation it adds has no effect on work items. A value
+// in the context a work item was given with wins over a base value with the same key.
+func WithBaseContextValues(values func(ctx context.Context) context.Context) Option {
+	return func(cfg *config) {
+		cfg.baseValues = values
+	}
+}
+
+// WithCategoryLimit limits how many work items in the given category may run at once. Work items are given a category
+// with AddWorkItemCategory. A worker skips work items in a category that is at its limit and takes the next one it may
+// run instead. Skipped work items are taken first once a work item in their category ends. Use it to keep a slow
+// category of work from taking every worker.
+func WithCategoryLimit(category string, max uint) Option {
+	return func(cfg *config) {
+		if cfg.categoryLimits == nil {
+			cfg.categoryLimits = make(map[string]uint)
+		}
+		cfg.categoryLimits[category] = max
+	}
+}
+
+// WithCircuitBreaker gives the Pool a circuit breaker. When threshold work items fail within the window, the breaker
+// opens and work items fail with ErrCircuitOpen without running. After the cooldown, the next work item runs as a
+// probe. If it succeeds, the breaker closes. If it fails, the breaker opens for another cooldown. A work item fails if
+// its Work function returns an error or its context ends first. A threshold of zero means no circuit breaker.
+func WithCircuitBreaker(threshold uint, window, cooldown time.Duration) Option {
+	return func(cfg *config) {
+		cfg.breakerCooldown = cooldown
+		cfg.breakerThreshold = threshold
+		cfg.breakerWindow = window
+	}
+}
+
+// WithClock makes the Pool use the given Clock for everything that depends on time. This is meant for tests.
+func WithClock(clock Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
+
+// WithContextFactory sets the function that creates the context for each work item given to the Pool with a nil
+// context. It is the place to apply default timeouts, values, and tracing for a Pool where every work item has the same
+// shape. The returned cancel function is called when the work item finishes. By default, the context is
+// context.Background.
+func WithContextFactory(factory ContextFactory) Option {
+	return func(cfg *config) {
+		cfg.contextFactory = factory
+	}
+}
+
+// WithDeadlockDetection makes the Pool break the deadlock that happens when every worker is waiting to add a work item
+// to its own Pool, which can only happen with WithSpillLimit, since re-entrant work items are otherwise queued right
+// away. When it is detected, ErrSelfDeadlock is reported to the error handler and the work item is queued beyond the
+// spill limit, so the workers can move on. Only submissions made with the context given to a Work function, or one
+// derived from it, are tracked. It is off by default.
+func WithDeadlockDetection() Option {
+	return func(cfg *config) {
+		cfg.deadlockDetection = true
+	}
+}
+
+// WithDeterministicDispatch is a debugging mode that makes the choices the Pool otherwise leaves to the Go runtime with
+// a pseudo-random number generator seeded with the given seed. Each queued work item is assigned to a worker by the
+// generator and only that worker will take it, even if others are idle. When the end of a work item's context, the
+// death of the Pool, and the end of the Work function happen at once, the generator picks which one the worker acts
+// on. With the same seed and a single producer, work items go to the same workers and races are decided the same way,
+// so a failure found by running tests in a loop can be replayed. The Go scheduler still decides when goroutines run.
+// This costs throughput and is not meant for production.
+func WithDeterministicDispatch(seed int64) Option {
+	return func(cfg *config) {
+		cfg.deterministic = true
+		cfg.seed = seed
+	}
+}
+
+// WithDispatchOrder sets which of the queued work items with the same priority a worker takes first. The default is
+// FIFO. Work items with a higher priority are always taken first.
+func WithDispatchOrder(order DispatchOrder) Option {
+	return func(cfg *config) {
+		cfg.dispatchOrder = order
+	}
+}
+
+// WithDrainOnKill makes Kill let the queued work items run instead of abandoning them. Kill stops the Pool from
+// accepting work items and returns right away, then the Pool dies once all the work is done. It is the same as calling
+// KillWithPolicy with the zero KillPolicy in another goroutine.
+func WithDrainOnKill() Option {
+	return func(cfg *config) {
+		cfg.drainOnKill = true
+	}
+}
+
+// WithFIFO makes work items enter the queue in the order they were given to the Pool. Without it, when several
+// goroutines are waiting for room in the queue, the one that gets it is chosen at random. With it, they wait in line.
+// With multiple producers, the order is the order the work items arrived at the Pool. This costs some throughput when
+// many producers are waiting. A Pool with 1 worker and WithFIFO runs work items in the order they arrived.
+func WithFIFO() Option {
+	return func(cfg *config) {
+		cfg.fifo = true
+	}
+}
+
+// WithFIFOGuarantee makes workers start work items in the order they were given to the Pool, as told by their WorkIDs,
+// even with many workers and producers. Work items may still finish in any order. A work item that is next in order,
+// but waiting for room, is let into the queue beyond its size, so it never holds up the work items behind it.
+// Priorities and WithDispatchOrder have no effect on the order, and a work item held back by WithCategoryLimit or a
+// serial lane holds back every work item after it. Unlike WithFIFO, it can't be changed by ReconfigureAndDrain.
+//
+// Without it, a Pool with 1 worker and a queue, see WithQueueSize, already starts work items in the order they were
+// given to the Pool, unless WithDispatchOrder asks for LIFO. Work items with a priority still go first there. Without a
+// queue, the worker takes whichever work item reaches it first.
+func WithFIFOGuarantee() Option {
+	return func(cfg *config) {
+		cfg.fifoGuarantee = true
+	}
+}
+
+// WithMaxErrorConcurrency limits how many goroutines may run the Pool's error handler at the same time. When the
+// limit is reached, errors wait for a running handler to return, which holds back the workers reporting them, and
+// Stats.HandlerSaturated is incremented. It keeps an error storm from starting an unbounded number of goroutines. Error
+// handlers given to single work items and errors reported after the Pool died are not limited. Zero means there is no
+// limit, which is the default.
+func WithMaxErrorConcurrency(n uint) Option {
+	return func(cfg *config) {
+		cfg.maxErrorHandlers = n
+	}
+}
+
+// WithMaxLifetime kills the Pool with ErrMaxLifetime once the duration has passed since New, regardless of any work
+// that is outstanding. It is a safety valve that keeps a stuck Pool from hanging a process forever. The deadline is
+// available from Pool.Deadline.
+func WithMaxLifetime(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.maxLifetime = d
+	}
+}
+
+// WithMaxQueuedBytes limits the total size of the work items that are queued or running, as given to AddWorkItemSized,
+// independent of how many there are. A work item that would go over the limit waits for room like one that finds the
+// queue full, or is handled by the RejectionPolicy. A work item is always let in when nothing else is counted, so one
+// bigger than the limit does not wait forever. Work items without a size and re-entrant work items are not limited.
+// Zero means there is no limit. See Stats.QueuedBytes.
+func WithMaxQueuedBytes(n int64) Option {
+	return func(cfg *config) {
+		cfg.maxQueuedBytes = n
+	}
+}
+
+// WithMaxRequeues sets how many times a work item may be requeued with Requeue before it fails with ErrRequeueLimit.
+// The default is DefaultMaxRequeues.
+func WithMaxRequeues(max uint) Option {
+	return func(cfg *config) {
+		cfg.maxRequeues = max
+	}
+}
+
+// WithOnBreakerChange sets a function that is called every time the state of the circuit breaker changes. See
+// WithCircuitBreaker. It is called from the goroutine of the work item that caused the change.
+func WithOnBreakerChange(onChange func(state BreakerState)) Option {
+	return func(cfg *config) {
+		cfg.onBreakerChange = onChange
+	}
+}
+
+// WithOnFinish sets a function that is called exactly once for every work item given to the Pool while it is alive
+// when the work item finishes, whether it succeeded, failed, was rejected, or was dropped. It is called before Wait can
+// return for the work item and may be called concurrently.
+func WithOnFinish(onFinish func(info FinishInfo)) Option {
+	return func(cfg *config) {
+		cfg.onFinish = onFinish
+	}
+}
+
+// WithOnWorkerRecycle sets a function that is called when a worker is replaced because of WithWorkerMaxItems or
+// WithWorkerMaxAge. It is given the worker's number, starting at 1, how many work items the worker processed, and how
+// long it lived. It is called by the worker after its replacement was started, so it should return quickly.
+func WithOnWorkerRecycle(onRecycle func(worker uint, processed uint64, age time.Duration)) Option {
+	return func(cfg *config) {
+		cfg.onRecycle = onRecycle
+	}
+}
+
+// WithOverflow gives the Pool a bounded overflow buffer of the given size. Work items are put in the overflow buffer
+// when all workers are busy and the queue is full. Each time this happens, Stats.Overflowed is incremented. When the
+// overflow buffer is also full, Stats.OverflowFull is incremented and the Pool's RejectionPolicy applies.
+func WithOverflow(size uint) Option {
+	return func(cfg *config) {
+		cfg.overflow = size
+	}
+}
+
+// WithPanicFunc sets the function that handles panics in Work functions when the PanicPolicy is Handler. It is called
+// from the goroutine of the Work function that panicked.
+func WithPanicFunc(handler PanicFunc) Option {
+	return func(cfg *config) {
+		cfg.panicFunc = handler
+	}
+}
+
+// WithPanicHandler sets a function that is called for every panic in a Work function instead of the error handler, so
+// panics can be kept apart from ordinary errors. It is called from the goroutine of the Work function that panicked.
+// It does not apply with the Repanic policy, or with the Handler policy when a function is set with WithPanicFunc.
+// Without it, panics are reported to the error handler as PanicErrors. Panics in the error handler itself are always
+// recovered and given to it, or logged if it is not set.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(cfg *config) {
+		cfg.panicHandler = handler
+	}
+}
+
+// WithPanicPolicy sets what happens when a Work function panics. Whatever the policy, a work item whose Work function
+// panicked has failed. The default is Recover.
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(cfg *config) {
+		cfg.panicPolicy = policy
+	}
+}
+
+// WithQueueSize gives the Pool a queue that holds the given number of work items when all workers are busy. By default,
+// there is no queue and AddWorkItem waits for a worker to be ready.
+func WithQueueSize(size uint) Option {
+	return func(cfg *config) {
+		cfg.queueSize = size
+	}
+}
+
+// WithRejectionPolicy sets what happens to a work item when all workers are busy and the queue and overflow buffer are
+// full. The default is Block.
+func WithRejectionPolicy(policy RejectionPolicy) Option {
+	return func(cfg *config) {
+		cfg.rejection = policy
+	}
+}
+
+// WithRetryBudget gives the Pool a retry budget shared by all of its work items, like gRPC's retry throttling, so a
+// failing dependency can't make every work item retry at once and bury it. The budget holds up to RetryBudgetTokens
+// tokens, or as many as set with WithRetryBudgetTokens, and starts full. Every failed call of a Work function, or attempt made by WithRetryPolicy, takes 1 token and
+// every successful one gives back the ratio. Retries, whether by WithRetryPolicy or Requeue, are only allowed while
+// more than half of the tokens are left. Otherwise, the work item fails right away with an error matching
+// ErrRetryBudget. A ratio of zero or less means there is no budget. See Stats.RetryBudget.
+func WithRetryBudget(ratio float64) Option {
+	return func(cfg *config) {
+		cfg.retryBudget = ratio
+	}
+}
+
+// WithRetryBudgetTokens sets how many tokens the retry budget set with WithRetryBudget holds. More tokens let more
+// retries through in a burst before the budget throttles them. Zero or less means the default, RetryBudgetTokens. It
+// has no effect without WithRetryBudget.
+func WithRetryBudgetTokens(tokens float64) Option {
+	return func(cfg *config) {
+		cfg.retryTokens = tokens
+	}
+}
+
+// WithReportNilWork makes the methods that do not return an error, such as AddWorkItem, report ErrNilWork to the error
+// handler when given a nil Work function, instead of panicking. The nil Work function is still never accepted. Use it
+// when Work functions are built at runtime and a nil one should not crash the caller.
+func WithReportNilWork() Option {
+	return func(cfg *config) {
+		cfg.reportNilWork = true
+	}
+}
+
+// WithSlowWorkThreshold sets a function that is given the data of every work item whose Work function ran longer than
+// the threshold, along with how long it ran. Use it to find the specific work items behind slow tails, which
+// aggregate statistics do not show. It is called when the work item finishes, before Wait can return for it, and may be
+// called concurrently. Work items that never started are not logged. It is off by default.
+func WithSlowWorkThreshold(threshold time.Duration, log func(data interface{}, duration time.Duration)) Option {
+	return func(cfg *config) {
+		cfg.slowLog = log
+		cfg.slowThreshold = threshold
+	}
+}
+
+// WithSpillLimit limits how many re-entrant work items may be queued beyond the queue and overflow buffer. Work items
+// added from within a Work function of the same Pool are re-entrant. They are spilled into the queue instead of waiting
+// for room, so a Work function can add work to its own Pool without deadlocking when every worker is doing the same.
+// The spilled work items are taken as workers free up. Without a limit, which is the default, memory grows with the
+// amount of re-entrant work. Past the limit, re-entrant work items are subject to the Pool's RejectionPolicy like any
+// other, so pair a limit with Reject or DropOldest to keep re-entrant submissions from blocking. A limit of zero means
+// no limit.
+func WithSpillLimit(max uint) Option {
+	return func(cfg *config) {
+		cfg.spillLimit = max
+	}
+}
+
+// WithWatchdog watches for Work functions that do not respect their context. When a worker stops waiting for a Work
+// function because its context ended or the Pool died, the Work function's goroutine is given the grace period to
+// return. If it has not returned by then, Stats.Leaked is incremented and the work item is reported by LeakedWork
+// until the goroutine returns, if it ever does.
+func WithWatchdog(grace time.Duration) Option {
+	return func(cfg *config) {
+		cfg.watchdog = grace
+	}
+}
+
+// WithWorkerInit sets a function that is called by each worker before it takes any work items, including workers that
+// replace others in RecycleWorkers or because of WithWorkerMaxAge or WithWorkerMaxItems. The worker's number is given,
+// starting at 1. It is the place to set up per-worker state, such as connections. If it returns an error, the error is
+// reported to the error handler as a WorkerError and the worker never takes work items.
+func WithWorkerInit(init func(worker uint) error) Option {
+	return func(cfg *config) {
+		cfg.workerInit = init
+	}
+}
+
+// WithWorkerMaxAge makes each worker retire once it has lived for the duration, after it finishes its current work
+// item. It runs its teardown hook and is replaced by a new worker, which runs the init hook, so the Pool is only a
+// worker short while the new one gets ready. Use it when Work functions or the libraries they use build up per-worker
+// state, such as memory, that is best thrown away now and then. A worker is not replaced while RecycleWorkers or
+// ReconfigureAndDrain is running, but is the next time it finishes a work item. Zero means there is no limit. See
+// WithOnWorkerRecycle.
+func WithWorkerMaxAge(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.workerMaxAge = d
+	}
+}
+
+// WithWorkerMaxItems is like WithWorkerMaxAge, but each worker retires once it has processed the given number of work
+// items. Zero means there is no limit.
+func WithWorkerMaxItems(n uint64) Option {
+	return func(cfg *config) {
+		cfg.workerMaxItems = n
+	}
+}
+
+// WithWorkerTeardown sets a function that is called by each worker that got ready when it stops taking work items
+// because the Pool died or the worker was retired by RecycleWorkers, WithWorkerMaxAge, or WithWorkerMaxItems. The
+// worker's number is given, starting at 1.
+func WithWorkerTeardown(teardown func(worker uint)) Option {
+	return func(cfg *config) {
+		cfg.workerTeardown = teardown
+	}
+}
@@ -0,0 +1,1047 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestDropOldest confirms that the DropOldest policy replaces the oldest queued work items with newer ones.
+func TestDropOldest(t *testing.T) {
+
+	// Create a wait pool that waits for the dropped work items to be reported.
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	// Keep track of the data of the dropped and done work items and a mutex for them.
+	mux := &sync.Mutex{}
+	dropped := make(map[interface{}]bool)
+	done := make(map[interface{}]bool)
+
+	// Create a worker pool with 1 worker and a queue of 2.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have the ctxerrpool.ErrDroppedOldest error.
+		var workErr *ctxerrpool.WorkError
+		if !errors.Is(err, ctxerrpool.ErrDroppedOldest) || !errors.As(err, &workErr) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+
+		mux.Lock()
+		defer mux.Unlock()
+		dropped[workErr.Data] = true
+	}, ctxerrpool.WithQueueSize(2), ctxerrpool.WithRejectionPolicy(ctxerrpool.DropOldest))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, 0)
+	<-started
+
+	// Fill the queue and then push out the oldest work items.
+	for i := 1; i < 5; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			mux.Lock()
+			defer mux.Unlock()
+			done[data] = true
+			return nil
+		}, i)
+	}
+
+	// Let the work finish.
+	close(gate)
+	pool.Wait()
+	wg.Wait()
+
+	// Confirm the oldest work items were dropped and the newest were done.
+	mux.Lock()
+	defer mux.Unlock()
+	if !dropped[1] || !dropped[2] || !done[3] || !done[4] || len(done) != 2 {
+		t.Errorf("Unexpected work items were dropped or done. Dropped: %v. Done: %v.", dropped, done)
+		t.FailNow()
+	}
+	if stats := pool.Stats(); stats.DroppedOldest != 2 {
+		t.Errorf("Expected 2 dropped work items, got %d.", stats.DroppedOldest)
+		t.FailNow()
+	}
+}
+
+// TestDropOldestPriority confirms that the DropOldest policy drops the oldest work item with the lowest priority, not
+// the one at the front of the queue.
+func TestDropOldestPriority(t *testing.T) {
+
+	// Keep track of the data of the dropped and done work items and a mutex for them.
+	mux := &sync.Mutex{}
+	dropped := make(map[interface{}]bool)
+	done := make(map[interface{}]bool)
+
+	// Create a worker pool with 1 worker and a queue of 2.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		var workErr *ctxerrpool.WorkError
+		if !errors.Is(err, ctxerrpool.ErrDroppedOldest) || !errors.As(err, &workErr) {
+			t.Errorf("An error occurred. Error: %v", err)
+			return
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		dropped[workErr.Data] = true
+	}, ctxerrpool.WithQueueSize(2), ctxerrpool.WithRejectionPolicy(ctxerrpool.DropOldest))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until told to stop.
+	release := busy(pool)
+
+	// Queue a work item with a low priority, then one with a high priority, then push one out.
+	work := func(workCtx context.Context, data interface{}) error {
+		mux.Lock()
+		defer mux.Unlock()
+		done[data] = true
+		return nil
+	}
+	for i, priority := range []int{0, 10, 0} {
+		_, err := pool.Submit(ctx, work, ctxerrpool.WithData(i), ctxerrpool.WithPriority(priority))
+		if err != nil {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}
+
+	// Let the work finish and confirm the low priority work item was dropped, not the high priority one.
+	release()
+	pool.Wait()
+	waitFor(t, func() bool {
+		mux.Lock()
+		defer mux.Unlock()
+		return len(dropped) == 1
+	})
+	mux.Lock()
+	defer mux.Unlock()
+	if !dropped[0] || !done[1] || !done[2] || len(done) != 2 {
+		t.Errorf("Unexpected work items were dropped or done. Dropped: %v. Done: %v.", dropped, done)
+		t.FailNow()
+	}
+}
+
+// TestWithBaseContextValues confirms that the base values are found in every work item's context, that the work item's
+// own values win, and that the base context's cancellation has no effect.
+func TestWithBaseContextValues(t *testing.T) {
+
+	// Create keys for the values.
+	type key string
+	service, env := key("service"), key("env")
+
+	// Create a worker pool with 1 worker and base values in a canceled context.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithBaseContextValues(func(ctx context.Context) context.Context {
+		ctx = context.WithValue(ctx, service, "api")
+		ctx = context.WithValue(ctx, env, "prod")
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		return ctx
+	}))
+	defer pool.Kill()
+
+	// Give the pool a work item with its own value for one of the keys and no deadline.
+	ctx := context.WithValue(context.Background(), env, "staging")
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		if workCtx.Err() != nil {
+			t.Errorf("The base context canceled the work item. Error: %v", workCtx.Err())
+		}
+		if _, ok := workCtx.Deadline(); ok {
+			t.Error("The work item has a deadline it was not given.")
+		}
+		child, cancel := context.WithCancel(workCtx)
+		defer cancel()
+		if child.Value(service) != "api" || child.Value(env) != "staging" {
+			t.Errorf("Unexpected values. Service: %v. Environment: %v", child.Value(service), child.Value(env))
+		}
+		return nil
+	}, nil)
+	pool.Wait()
+}
+
+// TestWithCategoryLimit confirms that no more than the limit of work items in a category run at once and that the
+// other work items are not held up by them.
+func TestWithCategoryLimit(t *testing.T) {
+
+	// Create a worker pool with 4 workers and a limit of 2 for a category.
+	pool := ctxerrpool.New(4, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithCategoryLimit("pdf", 2))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// Keep track of how many work items in the category run at once.
+	var running, most, other, done int64
+	pdf := func(workCtx context.Context, data interface{}) error {
+		defer atomic.AddInt64(&done, 1)
+		now := atomic.AddInt64(&running, 1)
+		for {
+			old := atomic.LoadInt64(&most)
+			if now <= old || atomic.CompareAndSwapInt64(&most, old, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 5)
+		atomic.AddInt64(&running, -1)
+		return nil
+	}
+
+	// Give the pool work items in the category and some that are not.
+	for i := 0; i < 20; i++ {
+		pool.AddWorkItemCategory(ctx, "pdf", pdf, i)
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			atomic.AddInt64(&other, 1)
+			return nil
+		}, i)
+	}
+	pool.Wait()
+
+	// Confirm the limit was respected and everything ran.
+	if most := atomic.LoadInt64(&most); most > 2 {
+		t.Errorf("Expected at most 2 work items in the category to run at once, got %d.", most)
+		t.FailNow()
+	}
+	if done, other := atomic.LoadInt64(&done), atomic.LoadInt64(&other); done != 20 || other != 20 {
+		t.Errorf("Expected all work items to run. Category: %d. Other: %d.", done, other)
+		t.FailNow()
+	}
+}
+
+// TestWithContextFactory confirms that Submit uses the Pool's context factory and releases each context it creates.
+func TestWithContextFactory(t *testing.T) {
+
+	// Count the contexts that are created and released.
+	var created, released int64
+	type key struct{}
+
+	// Create a worker pool with 2 workers and a context factory.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithContextFactory(func() (context.Context, context.CancelFunc) {
+		atomic.AddInt64(&created, 1)
+		ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Second)
+		return ctx, func() {
+			atomic.AddInt64(&released, 1)
+			cancel()
+		}
+	}))
+	defer pool.Kill()
+
+	// Submit work that needs the value from the context factory.
+	for i := 0; i < 10; i++ {
+		pool.Submit(nil, func(workCtx context.Context, data interface{}) error {
+			if workCtx.Value(key{}) != "value" {
+				t.Error("The work item's context was not made by the context factory.")
+				t.FailNow()
+			}
+			if _, ok := workCtx.Deadline(); !ok {
+				t.Error("The work item's context did not have the context factory's deadline.")
+				t.FailNow()
+			}
+			return nil
+		})
+	}
+
+	// Confirm every context was released once its work item finished.
+	pool.Wait()
+	if created := atomic.LoadInt64(&created); created != 10 || atomic.LoadInt64(&released) != created {
+		t.Errorf("Expected 10 contexts to be created and released. Created: %d. Released: %d.", created,
+			atomic.LoadInt64(&released))
+		t.FailNow()
+	}
+}
+
+// TestWithDeadlockDetection confirms that the deadlock of every worker waiting to add a work item to its own Pool is
+// reported and broken.
+func TestWithDeadlockDetection(t *testing.T) {
+
+	// Count the reported deadlocks.
+	var deadlocks int64
+
+	// Create a worker pool with 2 workers, no queue, and room for 1 spilled work item.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+
+		// This test case should only have the ctxerrpool.ErrSelfDeadlock error.
+		if !errors.Is(err, ctxerrpool.ErrSelfDeadlock) {
+			t.Errorf("An error occurred. Error: %v", err)
+		}
+		atomic.AddInt64(&deadlocks, 1)
+	}, ctxerrpool.WithSpillLimit(1), ctxerrpool.WithDeadlockDetection())
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Have both workers add 2 work items each once both are running.
+	var children int64
+	started := &sync.WaitGroup{}
+	started.Add(2)
+	for i := 0; i < 2; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			started.Done()
+			started.Wait()
+			for j := 0; j < 2; j++ {
+				pool.AddWorkItem(context.WithoutCancel(workCtx), func(workCtx context.Context, data interface{}) error {
+					atomic.AddInt64(&children, 1)
+					return nil
+				}, nil)
+			}
+			return nil
+		}, nil)
+	}
+
+	// Confirm the pool did not deadlock and the deadlock was reported.
+	done := make(chan struct{})
+	go func() {
+		pool.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Errorf("The pool deadlocked.")
+		t.FailNow()
+	}
+	if children := atomic.LoadInt64(&children); children != 4 {
+		t.Errorf("Expected 4 child work items to run, but %d did.", children)
+		t.FailNow()
+	}
+	if atomic.LoadInt64(&deadlocks) == 0 {
+		t.Errorf("The deadlock was not reported.")
+		t.FailNow()
+	}
+}
+
+// TestWithDeterministicDispatch confirms that the same seed gives work items to the same workers.
+func TestWithDeterministicDispatch(t *testing.T) {
+
+	// Run work items and record which worker took each one.
+	run := func(seed int64) []uint {
+		mux := &sync.Mutex{}
+		workers := make([]uint, 20)
+		pool := ctxerrpool.New(4, func(pool *ctxerrpool.Pool, err error) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}, ctxerrpool.WithDeterministicDispatch(seed), ctxerrpool.WithOnFinish(func(info ctxerrpool.FinishInfo) {
+			mux.Lock()
+			defer mux.Unlock()
+			workers[info.SubmitSeq-1] = info.Worker
+		}))
+		defer pool.Kill()
+
+		// Create a context.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		// Give the work items to the pool from one goroutine.
+		for i := 0; i < 20; i++ {
+			pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+				return nil
+			}, i)
+		}
+		pool.Wait()
+
+		mux.Lock()
+		defer mux.Unlock()
+		return workers
+	}
+
+	// Confirm the same seed gives the same workers and that more than one worker was used.
+	first := run(1)
+	used := make(map[uint]bool)
+	for i, worker := range run(1) {
+		if worker != first[i] {
+			t.Errorf("Work item %d went to worker %d, then worker %d.", i, first[i], worker)
+			t.FailNow()
+		}
+		used[worker] = true
+	}
+	if len(used) < 2 {
+		t.Errorf("Expected more than 1 worker to be used. Workers: %v", first)
+		t.FailNow()
+	}
+}
+
+// TestWithDispatchOrder confirms that the LIFO order makes workers take the newest queued work item first and that the
+// DropOldest policy still drops the oldest one.
+func TestWithDispatchOrder(t *testing.T) {
+
+	// Create a wait pool that waits for the dropped work item to be reported.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker, room for 3 queued work items, LIFO order, and the DropOldest policy.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have the first queued work item dropped.
+		var workErr *ctxerrpool.WorkError
+		if !errors.Is(err, ctxerrpool.ErrDroppedOldest) || !errors.As(err, &workErr) || workErr.Data != 1 {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithQueueSize(3), ctxerrpool.WithDispatchOrder(ctxerrpool.LIFO),
+		ctxerrpool.WithRejectionPolicy(ctxerrpool.DropOldest))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, 0)
+	<-started
+
+	// Queue 4 work items that record the order they ran in, so the first one is dropped.
+	var order []int
+	for i := 1; i <= 4; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			order = append(order, data.(int))
+			return nil
+		}, i)
+	}
+	wg.Wait()
+
+	// Confirm the newest work items ran first.
+	close(gate)
+	pool.Wait()
+	if len(order) != 3 || order[0] != 4 || order[1] != 3 || order[2] != 2 {
+		t.Errorf("The work items did not run in LIFO order. Order: %v", order)
+		t.FailNow()
+	}
+}
+
+// TestWithFIFO confirms that a Pool with 1 worker and WithFIFO runs work items in the order they arrived.
+func TestWithFIFO(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithFIFO())
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep track of the order the work items ran in and a mutex for it.
+	mux := &sync.Mutex{}
+	var order []int
+
+	// Give the pool numbered work items.
+	for i := 0; i < 20; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			mux.Lock()
+			defer mux.Unlock()
+			order = append(order, data.(int))
+			return nil
+		}, i)
+	}
+	pool.Wait()
+
+	// Confirm the order.
+	mux.Lock()
+	defer mux.Unlock()
+	for i, number := range order {
+		if i != number {
+			t.Errorf("Work items ran out of order: %v.", order)
+			t.FailNow()
+		}
+	}
+}
+
+// TestWithFIFOConcurrent confirms that producers waiting in line with WithFIFO do not get stuck, even when some of them
+// give up waiting.
+func TestWithFIFOConcurrent(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithFIFO())
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Have several producers give the pool work at once. Some of the work items give up waiting quickly.
+	producers := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		producers.Add(1)
+		go func(i int) {
+			defer producers.Done()
+			for j := 0; j < 20; j++ {
+				itemCtx, itemCancel := context.WithTimeout(ctx, time.Millisecond*time.Duration(1+i%2*100))
+				pool.AddWorkItem(itemCtx, func(workCtx context.Context, data interface{}) error {
+					time.Sleep(time.Millisecond)
+					return nil
+				}, j)
+				defer itemCancel()
+			}
+		}(i)
+	}
+	producers.Wait()
+
+	// Wait for the worker pool.
+	select {
+	case <-pool.Done():
+	case <-ctx.Done():
+		t.Error("The producers got stuck in line.")
+		t.FailNow()
+	}
+}
+
+// TestWithFIFOGuarantee confirms that a Pool with 1 worker and WithFIFOGuarantee runs work items in the order they were
+// given to it by many producers at once, and that producers waiting for room with more workers do not get stuck.
+func TestWithFIFOGuarantee(t *testing.T) {
+	testCases := []struct {
+		name    string
+		workers uint
+		options []ctxerrpool.Option
+	}{
+		{
+			name:    "1 worker",
+			workers: 1,
+		},
+		{
+			name:    "4 workers in line",
+			workers: 4,
+			options: []ctxerrpool.Option{ctxerrpool.WithFIFO()},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			// Create a worker pool with room for 2 queued work items.
+			options := append([]ctxerrpool.Option{ctxerrpool.WithFIFOGuarantee(), ctxerrpool.WithQueueSize(2)},
+				testCase.options...)
+			pool := ctxerrpool.New(testCase.workers, func(pool *ctxerrpool.Pool, err error) {}, options...)
+			defer pool.Kill()
+
+			// Create a context.
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			// Have several producers give the pool work at once, with priorities that would otherwise reorder it.
+			mux := sync.Mutex{}
+			var ids []ctxerrpool.WorkID
+			producers := &sync.WaitGroup{}
+			for i := 0; i < 8; i++ {
+				producers.Add(1)
+				go func(i int) {
+					defer producers.Done()
+					for j := 0; j < 20; j++ {
+						handle := &ctxerrpool.Handle{}
+						_, _ = pool.Submit(ctx, func(workCtx context.Context, data interface{}) error {
+							mux.Lock()
+							ids = append(ids, handle.ID())
+							mux.Unlock()
+							return nil
+						}, ctxerrpool.WithHandle(handle), ctxerrpool.WithPriority(j%3))
+					}
+				}(i)
+			}
+			producers.Wait()
+
+			// Wait for the worker pool.
+			select {
+			case <-pool.Done():
+			case <-ctx.Done():
+				t.Error("The producers got stuck.")
+				t.FailNow()
+			}
+
+			// Confirm every work item ran and, with 1 worker, in the order they were given to the pool.
+			mux.Lock()
+			defer mux.Unlock()
+			if len(ids) != 160 {
+				t.Errorf("Expected 160 work items to run, but %d did.", len(ids))
+				t.FailNow()
+			}
+			for i := 1; i < len(ids) && testCase.workers == 1; i++ {
+				if ids[i] < ids[i-1] {
+					t.Errorf("Work item %d ran after work item %d.", ids[i], ids[i-1])
+					t.FailNow()
+				}
+			}
+		})
+	}
+}
+
+// TestWithMaxErrorConcurrency confirms that no more error handlers run at the same time than allowed and that errors
+// waiting for one are counted.
+func TestWithMaxErrorConcurrency(t *testing.T) {
+
+	// Create a wait pool that waits for every error to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	// Create a worker pool with 3 workers whose error handlers are held until the gate is closed. Only 1 error handler
+	// may run at a time.
+	gate := make(chan struct{})
+	var running, most int64
+	pool := ctxerrpool.New(3, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+		if now := atomic.AddInt64(&running, 1); now > atomic.LoadInt64(&most) {
+			atomic.StoreInt64(&most, now)
+		}
+		<-gate
+		atomic.AddInt64(&running, -1)
+	}, ctxerrpool.WithMaxErrorConcurrency(1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool 3 work items that fail.
+	for i := 0; i < 3; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return errors.New("test")
+		}, i)
+	}
+
+	// Confirm the other errors wait for the running error handler.
+	waitFor(t, func() bool {
+		return pool.Stats().HandlerSaturated == 1 && pool.RunningHandlers() == 1
+	})
+
+	// Let the error handlers finish and confirm they ran one at a time.
+	close(gate)
+	wg.Wait()
+	if most := atomic.LoadInt64(&most); most != 1 {
+		t.Errorf("Expected 1 error handler at a time, but %d ran at once.", most)
+		t.FailNow()
+	}
+	waitFor(t, func() bool {
+		return pool.RunningHandlers() == 0
+	})
+}
+
+// TestWithOnFinish confirms that the function given with WithOnFinish sees every work item with its sequence numbers.
+func TestWithOnFinish(t *testing.T) {
+
+	// Keep track of the finished work items and a mutex for them.
+	mux := &sync.Mutex{}
+	var infos []ctxerrpool.FinishInfo
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithFIFO(),
+		ctxerrpool.WithOnFinish(func(info ctxerrpool.FinishInfo) {
+			mux.Lock()
+			defer mux.Unlock()
+			infos = append(infos, info)
+		}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool some work.
+	for i := 0; i < 5; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		}, i)
+	}
+	pool.Wait()
+
+	// With 1 worker, the work items should finish in the order they were given.
+	mux.Lock()
+	defer mux.Unlock()
+	if len(infos) != 5 {
+		t.Errorf("Expected 5 finished work items, got %d.", len(infos))
+		t.FailNow()
+	}
+	for i, info := range infos {
+		if info.SubmitSeq != uint64(i+1) || info.CompleteSeq != uint64(i+1) || info.Data != i || info.Duration <= 0 {
+			t.Errorf("Unexpected finish info: %+v.", info)
+			t.FailNow()
+		}
+	}
+}
+
+// TestWithOverflow confirms that work items spill into the overflow buffer when the queue is full and are rejected
+// when the overflow buffer is also full.
+func TestWithOverflow(t *testing.T) {
+
+	// Create a wait pool that waits for the error to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker, a queue of 1, and an overflow buffer of 1.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have the ctxerrpool.ErrQueueFull error.
+		if !errors.Is(err, ctxerrpool.ErrQueueFull) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithQueueSize(1), ctxerrpool.WithOverflow(1), ctxerrpool.WithRejectionPolicy(ctxerrpool.Reject))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{}, 3)
+	work := func(workCtx context.Context, data interface{}) error {
+		started <- struct{}{}
+		<-gate
+		return nil
+	}
+
+	// Occupy the worker. The queue has room for the work item even if the worker has not started yet.
+	pool.AddWorkItem(ctx, work, 0)
+	<-started
+
+	// Fill the queue, the overflow buffer, and then be rejected.
+	for i := 1; i < 4; i++ {
+		pool.AddWorkItem(ctx, work, i)
+	}
+
+	// Confirm the counters.
+	stats := pool.Stats()
+	if stats.Overflowed != 1 || stats.Rejected != 1 || stats.OverflowFull != 1 {
+		t.Errorf("Expected 1 overflowed, 1 rejected, and 1 full work item, got %d, %d, and %d.", stats.Overflowed,
+			stats.Rejected, stats.OverflowFull)
+		t.FailNow()
+	}
+
+	// Let the work finish.
+	close(gate)
+	pool.Wait()
+	wg.Wait()
+}
+
+// TestWithOverflowBlock confirms that a work item finding the overflow buffer full is counted once under the Block
+// policy, even though it is not rejected.
+func TestWithOverflowBlock(t *testing.T) {
+
+	// Create a worker pool with 1 worker, a queue of 1, and an overflow buffer of 1.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	}, ctxerrpool.WithQueueSize(1), ctxerrpool.WithOverflow(1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{}, 4)
+	work := func(workCtx context.Context, data interface{}) error {
+		started <- struct{}{}
+		<-gate
+		return nil
+	}
+
+	// Occupy the worker, then fill the queue and the overflow buffer.
+	pool.AddWorkItem(ctx, work, 0)
+	<-started
+	pool.AddWorkItem(ctx, work, 1)
+	pool.AddWorkItem(ctx, work, 2)
+
+	// Add a work item that waits for room and confirm it is counted as soon as it finds the overflow buffer full.
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		pool.AddWorkItem(ctx, work, 3)
+	}()
+	waitFor(t, func() bool {
+		return pool.Stats().OverflowFull == 1
+	})
+
+	// Let the work finish and confirm the waiting work item was only counted once and not rejected.
+	close(gate)
+	<-added
+	pool.Wait()
+	if stats := pool.Stats(); stats.OverflowFull != 1 || stats.Rejected != 0 {
+		t.Errorf("Expected 1 full and 0 rejected work items, got %d and %d.", stats.OverflowFull, stats.Rejected)
+		t.FailNow()
+	}
+}
+
+// TestWithReportNilWork confirms that nil Work functions are reported to the error handler instead of panicking and
+// are never accepted.
+func TestWithReportNilWork(t *testing.T) {
+
+	// Create a wait pool that waits for the 3 nil Work functions to be reported.
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+
+	// Create a worker pool with 1 worker that reports nil Work functions.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have the ctxerrpool.ErrNilWork error.
+		if !errors.Is(err, ctxerrpool.ErrNilWork) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithReportNilWork())
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool nil Work functions in different ways.
+	if id := pool.AddWorkItem(ctx, nil, nil); id != 0 {
+		t.Errorf("A nil Work function was accepted with ID %d.", id)
+		t.FailNow()
+	}
+	pool.AddFunc(ctx, nil)
+	errs := pool.RunAll(ctx, []ctxerrpool.Work{nil, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}})
+	if !errors.Is(errs[0], ctxerrpool.ErrNilWork) || errs[1] != nil {
+		t.Errorf("RunAll returned the wrong errors. Errors: %v", errs)
+		t.FailNow()
+	}
+
+	// Confirm every nil Work function was reported.
+	wg.Wait()
+	pool.Wait()
+}
+
+// TestWithRetryBudget confirms that retries by WithRetryPolicy and Requeue are refused once the Pool's retry budget is
+// used up.
+func TestWithRetryBudget(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a retry budget.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithRetryBudget(0.1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool work items that always fail and retry up to 5 times, one after the other.
+	errTest := errors.New("test")
+	policy := ctxerrpool.RetryPolicy{
+		Attempts: 5,
+	}
+	submit := func(work ctxerrpool.Work, options ...ctxerrpool.SubmitOption) error {
+		errs := make(chan error, 1)
+		_, _ = pool.Submit(ctx, work, append(options, ctxerrpool.WithCallback(func(err error) {
+			errs <- err
+		}))...)
+		return <-errs
+	}
+	var attempts int64
+	fail := func(workCtx context.Context, data interface{}) error {
+		atomic.AddInt64(&attempts, 1)
+		return errTest
+	}
+
+	// The first work item uses half of the budget, so the second one is not retried.
+	if err := submit(fail, ctxerrpool.WithRetryPolicy(policy)); errors.Is(err, ctxerrpool.ErrRetryBudget) ||
+		atomic.LoadInt64(&attempts) != 5 {
+		t.Errorf("Expected the first work item to make every attempt.\nAttempts: %d\nError: %v",
+			atomic.LoadInt64(&attempts), err)
+		t.FailNow()
+	}
+	if err := submit(fail, ctxerrpool.WithRetryPolicy(policy)); !errors.Is(err, ctxerrpool.ErrRetryBudget) ||
+		!errors.Is(err, errTest) || atomic.LoadInt64(&attempts) != 6 {
+		t.Errorf("Expected the second work item not to be retried.\nAttempts: %d\nError: %v",
+			atomic.LoadInt64(&attempts), err)
+		t.FailNow()
+	}
+
+	// Confirm a work item can't requeue itself either.
+	err := submit(func(workCtx context.Context, data interface{}) error {
+		return ctxerrpool.Requeue(0, errTest)
+	})
+	if !errors.Is(err, ctxerrpool.ErrRetryBudget) || !errors.Is(err, errTest) {
+		t.Errorf("Expected the work item not to be requeued. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the budget and the refused retries are in the stats.
+	if stats := pool.Stats(); stats.RetryBudget != 3 || stats.RetryThrottled != 2 {
+		t.Errorf("Unexpected stats.\nRetry budget: %v\nRetry throttled: %d", stats.RetryBudget, stats.RetryThrottled)
+		t.FailNow()
+	}
+}
+
+// TestWithRetryBudgetTokens confirms that the retry budget holds the configured number of tokens and only allows
+// retries while more than half of them are left.
+func TestWithRetryBudgetTokens(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a retry budget of 4 tokens.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithRetryBudget(0.1),
+		ctxerrpool.WithRetryBudgetTokens(4))
+	defer pool.Kill()
+
+	// Confirm the budget starts full.
+	if budget := pool.Stats().RetryBudget; budget != 4 {
+		t.Errorf("Expected a budget of 4 tokens, got %v.", budget)
+		t.FailNow()
+	}
+
+	// Give the pool a work item that always fails and retries up to 5 times.
+	errTest := errors.New("test")
+	var attempts int64
+	errs := make(chan error, 1)
+	_, _ = pool.Submit(context.Background(), func(workCtx context.Context, data interface{}) error {
+		atomic.AddInt64(&attempts, 1)
+		return errTest
+	}, ctxerrpool.WithRetryPolicy(ctxerrpool.RetryPolicy{Attempts: 5}), ctxerrpool.WithCallback(func(err error) {
+		errs <- err
+	}))
+
+	// Confirm it stopped retrying once only half of the tokens were left.
+	if err := <-errs; !errors.Is(err, ctxerrpool.ErrRetryBudget) || atomic.LoadInt64(&attempts) != 2 {
+		t.Errorf("Expected the work item to be throttled after 2 attempts.\nAttempts: %d\nError: %v",
+			atomic.LoadInt64(&attempts), err)
+		t.FailNow()
+	}
+	if stats := pool.Stats(); stats.RetryBudget != 2 || stats.RetryThrottled != 1 {
+		t.Errorf("Unexpected stats.\nRetry budget: %v\nRetry throttled: %d", stats.RetryBudget, stats.RetryThrottled)
+		t.FailNow()
+	}
+}
+
+// TestWithSlowWorkThreshold confirms that only work items that ran longer than the threshold are logged.
+func TestWithSlowWorkThreshold(t *testing.T) {
+
+	// Keep track of the logged work items and a mutex for them.
+	mux := &sync.Mutex{}
+	var logged []interface{}
+
+	// Create a worker pool with 2 workers that logs work items slower than 20 milliseconds.
+	threshold := 20 * time.Millisecond
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	}, ctxerrpool.WithSlowWorkThreshold(threshold, func(data interface{}, duration time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		if duration <= threshold {
+			t.Errorf("A work item was logged that was not slow. Duration: %s", duration)
+		}
+		logged = append(logged, data)
+	}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a slow work item and a fast one.
+	work := func(workCtx context.Context, data interface{}) error {
+		if data == "slow" {
+			time.Sleep(2 * threshold)
+		}
+		return nil
+	}
+	pool.AddWorkItem(ctx, work, "slow")
+	pool.AddWorkItem(ctx, work, "fast")
+	pool.Wait()
+
+	// Confirm only the slow work item was logged.
+	mux.Lock()
+	defer mux.Unlock()
+	if len(logged) != 1 || logged[0] != "slow" {
+		t.Errorf("Expected only the slow work item to be logged. Logged: %v", logged)
+		t.FailNow()
+	}
+}
+
+// TestWithSpillLimit confirms that re-entrant work items are spilled into the queue up to the spill limit and are
+// subject to the RejectionPolicy past it.
+func TestWithSpillLimit(t *testing.T) {
+
+	// Create a wait pool that waits for the errors to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker, a queue of 1, and a spill limit of 2 that rejects work items past it.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have the ctxerrpool.ErrQueueFull error.
+		if !errors.Is(err, ctxerrpool.ErrQueueFull) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithQueueSize(1), ctxerrpool.WithSpillLimit(2),
+		ctxerrpool.WithRejectionPolicy(ctxerrpool.Reject))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Count the children that ran.
+	var children int64
+
+	// Have the only worker add 4 children to its own pool synchronously. The first fits in the queue, the next 2 are
+	// spilled, and the last is rejected.
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		for i := 0; i < 4; i++ {
+			pool.AddWorkItem(context.WithoutCancel(workCtx), func(workCtx context.Context, data interface{}) error {
+				atomic.AddInt64(&children, 1)
+				return nil
+			}, i)
+		}
+		return nil
+	}, "parent")
+
+	// Wait for the worker pool.
+	select {
+	case <-pool.Done():
+	case <-ctx.Done():
+		t.Error("The re-entrant work deadlocked.")
+		t.FailNow()
+	}
+	wg.Wait()
+
+	// Confirm the counters.
+	stats := pool.Stats()
+	if stats.Spilled != 2 || stats.Rejected != 1 || atomic.LoadInt64(&children) != 3 {
+		t.Errorf("Expected 2 spilled, 1 rejected, and 3 children run, got %d, %d, and %d.", stats.Spilled,
+			stats.Rejected, atomic.LoadInt64(&children))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,351 @@
+package ctxerrpool_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestHandlerPanic confirms that a panic in the error handler is recovered and logged and that later errors are still
+// handled.
+func TestHandlerPanic(t *testing.T) {
+
+	// Capture the log.
+	buf := &syncBuffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	// Create a wait pool that waits for both errors to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	// Create a worker pool with 1 worker whose error handler panics on the first error.
+	var handled int64
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+		if atomic.AddInt64(&handled, 1) == 1 {
+			panic("handler bug")
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool two work items that fail one after the other.
+	for i := 0; i < 2; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return errors.New("fail")
+		}, i)
+		pool.Wait()
+	}
+	wg.Wait()
+
+	// Confirm the panic was logged.
+	waitFor(t, func() bool {
+		return strings.Contains(buf.String(), "handler bug")
+	})
+}
+
+// TestHandlerPanicWithPanicHandler confirms that a panic in the error handler is given to the panic handler and that
+// later errors are still handled.
+func TestHandlerPanicWithPanicHandler(t *testing.T) {
+
+	// Create a wait pool that waits for the panic and the second error to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	// Create a worker pool with 1 worker whose error handler panics on the first error.
+	var handled int64
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		if atomic.AddInt64(&handled, 1) == 1 {
+			panic("handler bug")
+		}
+		wg.Done()
+	}, ctxerrpool.WithPanicHandler(func(pool *ctxerrpool.Pool, recovered interface{}, stack []byte) {
+		defer wg.Done()
+		if recovered != "handler bug" || len(stack) == 0 {
+			t.Errorf("The panic handler was not called correctly. Recovered: %v", recovered)
+		}
+	}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool two work items that fail one after the other.
+	for i := 0; i < 2; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return errors.New("fail")
+		}, i)
+		pool.Wait()
+	}
+	wg.Wait()
+}
+
+// TestPanicRecover confirms that a panicking Work function is reported to the error handler with its stack trace by
+// default and that the worker keeps taking work items.
+func TestPanicRecover(t *testing.T) {
+
+	// Create a wait pool that waits for the panic to be reported.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have a PanicError with the data, the value given to panic, and the stack trace.
+		var panicErr *ctxerrpool.PanicError
+		if !errors.Is(err, ctxerrpool.ErrWorkPanicked) || !errors.As(err, &panicErr) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+		if panicErr.Data != 1 || panicErr.Recovered != "boom" || len(panicErr.Stack) == 0 {
+			t.Errorf("The panic was not reported correctly.\nData: %v\nRecovered: %v", panicErr.Data,
+				panicErr.Recovered)
+			t.FailNow()
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a work item that panics.
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		panic("boom")
+	}, 1)
+	wg.Wait()
+
+	// Confirm that the worker is still taking work items.
+	done := false
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		done = true
+		return nil
+	}, 2)
+	pool.Wait()
+	if !done {
+		t.Errorf("The worker did not take a work item after a panic.")
+		t.FailNow()
+	}
+}
+
+// TestPanicStack confirms that a PanicError gives the stack trace of the panic through PanicStack and %+v.
+func TestPanicStack(t *testing.T) {
+
+	// Create a worker pool with 1 worker that keeps the reported error.
+	errs := make(chan error, 1)
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		errs <- err
+	})
+	defer pool.Kill()
+
+	// Give the pool a work item that panics.
+	pool.AddWorkItem(context.Background(), panickingWork, nil)
+	reported := <-errs
+
+	// Confirm the stack trace mentions the function that panicked.
+	var panicErr *ctxerrpool.PanicError
+	if !errors.As(reported, &panicErr) {
+		t.Errorf("Expected a PanicError, but got: %v", reported)
+		t.FailNow()
+	}
+	if !strings.Contains(string(panicErr.PanicStack()), "panickingWork") {
+		t.Errorf("The stack trace does not mention the function that panicked.\nStack: %s", panicErr.PanicStack())
+		t.FailNow()
+	}
+
+	// Confirm the stack trace is only formatted with %+v.
+	if formatted := fmt.Sprintf("%+v", reported); !strings.Contains(formatted, "panickingWork") ||
+		!strings.HasPrefix(formatted, reported.Error()) {
+		t.Errorf("The stack trace was not formatted with %%+v.\nFormatted: %s", formatted)
+		t.FailNow()
+	}
+	if formatted := fmt.Sprintf("%v", reported); formatted != reported.Error() {
+		t.Errorf("Expected %%v to only format the error.\nFormatted: %s", formatted)
+		t.FailNow()
+	}
+}
+
+// TestPanicUnwrap confirms that a PanicError unwraps to the value given to panic if it is an error.
+func TestPanicUnwrap(t *testing.T) {
+
+	// Create an error to panic with.
+	errPanic := errors.New("panic error")
+
+	// Create a wait pool that waits for the panic to be reported.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have the error given to panic.
+		if !errors.Is(err, ctxerrpool.ErrWorkPanicked) || !errors.Is(err, errPanic) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a work item that panics with an error.
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		panic(errPanic)
+	}, nil)
+	wg.Wait()
+}
+
+// TestWithPanicHandler confirms that panics go to the panic handler instead of the error handler and that ordinary
+// errors still go to the error handler.
+func TestWithPanicHandler(t *testing.T) {
+
+	// Create an ordinary error.
+	errOrdinary := errors.New("ordinary")
+
+	// Keep track of the handled errors and panics and a mutex for them.
+	mux := &sync.Mutex{}
+	var errs []error
+	var recovered []interface{}
+	var stack []byte
+
+	// Create a worker pool with 1 worker that gives panics to the panic handler.
+	var pool *ctxerrpool.Pool
+	pool = ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		errs = append(errs, err)
+	}, ctxerrpool.WithPanicHandler(func(p *ctxerrpool.Pool, r interface{}, s []byte) {
+		mux.Lock()
+		defer mux.Unlock()
+		if p != pool {
+			t.Errorf("The panic handler was given the wrong pool.")
+		}
+		recovered = append(recovered, r)
+		stack = s
+	}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a work item that panics and one that returns an ordinary error.
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		panic("boom")
+	}, nil)
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		return errOrdinary
+	}, nil)
+	pool.Wait()
+
+	// Wait for the ordinary error to be handled.
+	waitFor(t, func() bool {
+		mux.Lock()
+		defer mux.Unlock()
+		return len(errs) > 0
+	})
+
+	// Confirm each went to its own handler.
+	mux.Lock()
+	defer mux.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], errOrdinary) {
+		t.Errorf("The error handler was not called correctly. Errors: %v", errs)
+		t.FailNow()
+	}
+	if len(recovered) != 1 || recovered[0] != "boom" || len(stack) == 0 {
+		t.Errorf("The panic handler was not called correctly. Recovered: %v", recovered)
+		t.FailNow()
+	}
+}
+
+// TestWithPanicPolicy confirms that the Handler policy gives panics to the panic function instead of the error handler
+// and that the work item still fails.
+func TestWithPanicPolicy(t *testing.T) {
+
+	// Keep track of the panic and a mutex for it.
+	mux := &sync.Mutex{}
+	var data, recovered interface{}
+	var stack []byte
+	var finishErr error
+
+	// Create a worker pool with 1 worker that gives panics to the panic function.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("The error handler was called for a panic. Error: %v", err)
+	}, ctxerrpool.WithPanicPolicy(ctxerrpool.Handler), ctxerrpool.WithPanicFunc(func(d, r interface{}, s []byte) {
+		mux.Lock()
+		defer mux.Unlock()
+		data, recovered, stack = d, r, s
+	}), ctxerrpool.WithOnFinish(func(info ctxerrpool.FinishInfo) {
+		mux.Lock()
+		defer mux.Unlock()
+		finishErr = info.Err
+	}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a work item that panics.
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		panic("boom")
+	}, 1)
+	pool.Wait()
+
+	// Confirm the panic function got the panic and the work item failed.
+	mux.Lock()
+	defer mux.Unlock()
+	if data != 1 || recovered != "boom" || len(stack) == 0 {
+		t.Errorf("The panic function was not called correctly.\nData: %v\nRecovered: %v", data, recovered)
+		t.FailNow()
+	}
+	if !errors.Is(finishErr, ctxerrpool.ErrWorkPanicked) {
+		t.Errorf("The work item did not fail with the panic. Error: %v", finishErr)
+		t.FailNow()
+	}
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	buf bytes.Buffer
+	mux sync.Mutex
+}
+
+// String returns the contents of the buffer.
+func (b *syncBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.String()
+}
+
+// Write implements the io.Writer interface.
+func (b *syncBuffer) Write(p []byte) (n int, err error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+// panickingWork is a Work function that panics.
+func panickingWork(workCtx context.Context, data interface{}) error {
+	var items []int
+	return fmt.Errorf("unreachable: %d", items[1])
+}
@@ -0,0 +1,43 @@
+package ctxerrpool
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultParent holds the context set with SetDefaultParent.
+var defaultParent = struct {
+	ctx context.Context
+	mux sync.Mutex
+}{}
+
+// SetDefaultParent sets a context that every Pool created by New afterwards is tied to. When the context ends, those
+// Pools are killed with its cause, as with KillCause. It is meant for programs that create Pools dynamically, such as
+// per request or per job, so they all end with one root context. Pools created before the call are unaffected. A nil
+// context removes the default parent.
+//
+// The default parent is global state shared by everything in the program that creates Pools, including libraries, so
+// it is best set once, early in main.
+func SetDefaultParent(ctx context.Context) {
+	defaultParent.mux.Lock()
+	defaultParent.ctx = ctx
+	defaultParent.mux.Unlock()
+}
+
+// inherit ties the Pool to the default parent, if there is one. Nothing is left watching the parent once the Pool dies.
+func (g *Pool) inherit() {
+	defaultParent.mux.Lock()
+	parent := defaultParent.ctx
+	defaultParent.mux.Unlock()
+	if parent == nil {
+		return
+	}
+
+	// Kill the Pool when the parent ends and stop watching the parent when the Pool dies.
+	stop := context.AfterFunc(parent, func() {
+		g.KillCause(context.Cause(parent))
+	})
+	context.AfterFunc(g.ctx, func() {
+		stop()
+	})
+}
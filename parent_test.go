@@ -0,0 +1,54 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestSetDefaultParent confirms that Pools created after the default parent is set die when it ends and that Pools
+// created before are unaffected.
+func TestSetDefaultParent(t *testing.T) {
+
+	// Create a worker pool before the default parent is set.
+	before := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	})
+	defer before.Kill()
+
+	// Set the default parent and create a worker pool after it.
+	errRoot := errors.New("root")
+	parent, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	ctxerrpool.SetDefaultParent(parent)
+	defer ctxerrpool.SetDefaultParent(nil)
+	after := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	})
+	defer after.Kill()
+
+	// End the default parent.
+	cancel(errRoot)
+
+	// Confirm the pool created after it died with its cause.
+	select {
+	case <-after.Death():
+	case <-time.After(time.Second):
+		t.Errorf("The pool did not die when the default parent ended.")
+		t.FailNow()
+	}
+	if cause := context.Cause(after.AsContext()); !errors.Is(cause, ctxerrpool.ErrPoolKilled) ||
+		!errors.Is(cause, errRoot) {
+		t.Errorf("The pool died with the wrong cause. Cause: %v", cause)
+		t.FailNow()
+	}
+
+	// Confirm the pool created before it is still alive.
+	if before.Dead() {
+		t.Errorf("The pool created before the default parent was set died.")
+		t.FailNow()
+	}
+}
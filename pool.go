@@ -2,120 +2,841 @@ package ctxerrpool
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// ErrorHandler is a function that receives an error and handles it.
-type ErrorHandler func(pool Pool, err error)
+// ErrorHandler is a function that receives an error and handles it. Each error is handled in its own goroutine, which
+// holds none of the Pool's locks, so the handler may call the Pool's introspection methods, such as Stats, InFlight,
+// Pending, PendingItems, and LoadFactor, to log the load alongside the error. A panic in the handler is recovered and
+// does not stop later errors from being handled. See WithPanicHandler.
+//
+// Before the Pool was used through a pointer, the handler received a Pool value. Such handlers must be changed to take
+// a *Pool. There is no alias for the old signature, since a Pool must not be copied.
+type ErrorHandler func(pool *Pool, err error)
 
-// Pool is the way to control a pool of worker goroutines that understand context.Context and error handling.
+// PanicHandler is a function that handles a panic in a Work function instead of the ErrorHandler. It is given the
+// value given to panic and the stack trace of the goroutine that panicked. See WithPanicHandler.
+type PanicHandler func(pool *Pool, recovered interface{}, stack []byte)
+
+// ProfileLabel is the pprof label key given to every goroutine a Pool starts, including the ones running Work
+// functions and error handlers. Its value is the Pool's address, as formatted by the %p verb. It tells the goroutines
+// of different Pools apart in goroutine profiles.
+const ProfileLabel = "ctxerrpool"
+
+// IOMultiplier is how many workers NewIO starts for each CPU the Go scheduler may use. Work that mostly waits on the
+// network or disk leaves CPUs idle, so it needs more workers than there are CPUs to keep them busy.
+const IOMultiplier = 4
+
+// Pool is the way to control a pool of worker goroutines that understand context.Context and error handling. A Pool
+// must be created with New and must not be copied.
 type Pool struct {
-	death   chan struct{}
-	do      chan<- *workItem
-	errChan chan error
-	wg      *sync.WaitGroup
+	noCopy noCopy
+
+	awaitMux    sync.Mutex
+	awaiters    []*awaiter
+	awaiting    int64
+	base        context.Context
+	breaker     *breaker
+	budget      *retryBudget
+	cancel      context.CancelCauseFunc
+	cfg         config
+	cseq        uint64
+	ctx         context.Context
+	deadline    time.Time
+	death       chan struct{}
+	errChan     chan error
+	events      events
+	handler     ErrorHandler
+	handlers    int64
+	handling    chan struct{}
+	idlers      idlers
+	labels      context.Context
+	leaks       leaks
+	name        atomic.Pointer[string]
+	pending     int64
+	queue       *queue
+	recycle     sync.Mutex
+	started     chan struct{}
+	starting    int64
+	stats       stats
+	wg          sync.WaitGroup
+	workerMux   sync.Mutex
+	workerStats []workerStats
+	workers     []*worker
+	working     sync.WaitGroup
 }
 
-// New creates a new Pool.
-func New(workers uint, errorHandler ErrorHandler) Pool {
+// New creates a new Pool. Options may be given to change its default behavior. It used to return a Pool value. Fields
+// and variables that held one must now hold the *Pool, since a Pool must not be copied.
+func New(workers uint, errorHandler ErrorHandler, options ...Option) *Pool {
+
+	// Apply the options.
+	cfg := newConfig(options)
 
 	// Create the required channels and wait pool.
 	death := make(chan struct{})
-	do := make(chan *workItem)
 	errChan := make(chan error)
-	wg := &sync.WaitGroup{}
+	q := newQueue(cfg, death, workers)
+
+	// Create the context that is canceled when the Pool dies.
+	ctx, cancel := context.WithCancelCause(context.Background())
 
 	// Make the Pool.
-	pool := Pool{
+	pool := &Pool{
+		breaker: newBreaker(cfg),
+		budget:  newRetryBudget(cfg.retryBudget, cfg.retryTokens),
+		cancel:  cancel,
+		cfg:     cfg,
+		ctx:     ctx,
 		death:   death,
-		do:      do,
 		errChan: errChan,
-		wg:      wg,
+		handler: errorHandler,
+		queue:   q,
+	}
+	if cfg.baseValues != nil {
+		pool.base = cfg.baseValues(context.Background())
+	}
+	if cfg.maxErrorHandlers > 0 {
+		pool.handling = make(chan struct{}, cfg.maxErrorHandlers)
 	}
 
+	// Label the goroutines the Pool starts, so they can be found in goroutine profiles.
+	pool.labels = pprof.WithLabels(context.Background(), pprof.Labels(ProfileLabel, fmt.Sprintf("%p", pool)))
+
 	// Handle all outgoing errors async.
-	go pool.handleErrors(errorHandler)
+	pool.spawn(pool.handleErrors)
 
-	// Create the desired number of workers and start them.
-	for i := uint(0); i < workers; i++ {
-		w := worker{
-			death:   death,
-			do:      do,
-			errChan: errChan,
-		}
-		go w.start()
+	// Create the desired number of workers and start them. Started is closed once they are all ready.
+	pool.started = make(chan struct{})
+	pool.starting = int64(workers)
+	if workers == 0 {
+		close(pool.started)
+	}
+	pool.workerStats = make([]workerStats, workers)
+	pool.workers = make([]*worker, workers)
+	for i := range pool.workers {
+		w := pool.newWorker(uint(i) + 1)
+		w.initial = true
+		pool.workers[i] = w
+		pool.spawn(w.start)
+	}
+
+	// Kill the Pool when the default parent ends, if there is one.
+	pool.inherit()
+
+	// Kill the Pool when it reaches its maximum lifetime, if configured to.
+	if cfg.maxLifetime > 0 {
+		pool.deadline = cfg.clock.Now().Add(cfg.maxLifetime)
+		timer := cfg.clock.NewTimer(cfg.maxLifetime)
+		pool.spawn(func() {
+			pool.expire(timer)
+		})
 	}
 
 	return pool
 }
 
+// NewDefault creates a new Pool with a worker for each CPU the Go scheduler may use, as told by runtime.GOMAXPROCS when
+// it is called, but at least 1. It suits CPU-bound work. Options may be given to change its default behavior.
+func NewDefault(errorHandler ErrorHandler, options ...Option) *Pool {
+	return New(defaultWorkers(), errorHandler, options...)
+}
+
+// NewIO is like NewDefault, but starts IOMultiplier times as many workers. It suits work that mostly waits on the
+// network or disk.
+func NewIO(errorHandler ErrorHandler, options ...Option) *Pool {
+	return New(defaultWorkers()*IOMultiplier, errorHandler, options...)
+}
+
+// defaultWorkers returns the number of workers NewDefault starts.
+func defaultWorkers() uint {
+	return uint(max(runtime.GOMAXPROCS(0), 1))
+}
+
+// Deadline returns when the Pool will be killed because of its maximum lifetime. ok is false if there is no maximum
+// lifetime. See WithMaxLifetime.
+func (g *Pool) Deadline() (deadline time.Time, ok bool) {
+	return g.deadline, !g.deadline.IsZero()
+}
+
 // Death returns a channel that will close when the Pool has died.
-func (g Pool) Death() <-chan struct{} {
+func (g *Pool) Death() <-chan struct{} {
 	return g.death
 }
 
-// AddWorkItem takes in context information and a Work function and gives it to a worker. This can block if all workers
-// are busy and the work item buffer is full. This function will block if no workers are ready. Call with the go keyword
-// to launch it in another goroutine to guarantee no blocking.
-func (g Pool) AddWorkItem(ctx context.Context, work Work, data interface{}) {
+// AddWorkItem takes in context information and a Work function and gives it to a worker. This function will block if
+// no workers are ready and there is no room in the queue or overflow buffer, unless the Pool's RejectionPolicy says
+// otherwise. Call with the go keyword to launch it in another goroutine to guarantee no blocking.
+//
+// Submissions made from within a Work function are re-entrant. When the given context is the one the Pool gave to a
+// running Work function, or is derived from it, the work item is queued without waiting for a worker to be ready. This
+// means a Work function can add more work to its own Pool without the go keyword and without deadlocking when every
+// worker is busy. Use context.WithoutCancel to keep a follow-up work item alive after the current one finishes. See
+// WithSpillLimit to bound how many re-entrant work items may be queued.
+//
+// The returned WorkID identifies the work item for later queries. It is assigned before the work item is queued. It is
+// zero if the Pool was already dead.
+//
+// If the context is nil, it is created by the Pool's context factory, as with Submit. A nil Work function is a
+// programming error and AddWorkItem panics with ErrNilWork without accepting it, unless WithReportNilWork is set, in
+// which case ErrNilWork is reported to the error handler and zero is returned.
+func (g *Pool) AddWorkItem(ctx context.Context, work Work, data interface{}) WorkID {
+	return g.addWorkItem(ctx, &workItem{
+		work: work,
+		data: data,
+	})
+}
 
-	// Check to make sure the pool isn't dead on arrival.
-	if g.Dead() {
-		return
+// AddWorkItemCategory is like AddWorkItem, but the work item belongs to the given category. How many work items in a
+// category may run at once is limited with WithCategoryLimit. Categories without a limit are not limited.
+func (g *Pool) AddWorkItemCategory(ctx context.Context, category string, work Work, data interface{}) WorkID {
+	return g.addWorkItem(ctx, &workItem{
+		category: category,
+		work:     work,
+		data:     data,
+	})
+}
+
+// AddSerial is like AddWorkItem, but the work item belongs to the given serial lane. Work items in the same lane run
+// one at a time, in the order they were given to the Pool, while work items in other lanes and those without a lane
+// run alongside them. Use it for work that must never run at the same time as itself, such as writing to a file. A
+// lane is created when its first work item is given to the Pool and removed once it has no work items left. An empty
+// lane name means no lane. See LaneDepths.
+func (g *Pool) AddSerial(ctx context.Context, lane string, work Work, data interface{}) WorkID {
+	return g.addWorkItem(ctx, &workItem{
+		lane: lane,
+		work: work,
+		data: data,
+	})
+}
+
+// AddWorkItemDeadline is like AddWorkItem, but the work item's context ends at the given deadline. The deadline can
+// only tighten the context's own deadline, so a later one is ignored. Time spent waiting in the queue counts. If the
+// context is nil, it is created by the Pool's context factory.
+func (g *Pool) AddWorkItemDeadline(ctx context.Context, deadline time.Time, work Work, data interface{}) WorkID {
+	if work == nil {
+		g.refuseNilWork()
+		return 0
 	}
 
-	// Increment the wait pool.
-	g.wg.Add(1)
+	// Derive the context with the deadline, which is released when the work item finishes.
+	var release context.CancelFunc
+	if ctx == nil {
+		ctx, release = g.cfg.contextFactory()
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+
+	return g.addWorkItem(ctx, &workItem{
+		release: chain(release, cancel),
+		work:    work,
+		data:    data,
+	})
+}
 
-	// Create a cancellable context.
-	workCtx, cancel := context.WithCancel(ctx)
+// Cancel cancels the context of the work item with the given ID without affecting the rest of the Pool. A queued work
+// item is removed from the queue and reported to the error handler with context.Canceled. A running work item is told
+// to end through its context. It returns false if the work item has already finished or never existed.
+func (g *Pool) Cancel(id WorkID) bool {
+	item, queued, ok := g.queue.cancel(uint64(id))
+	if !ok {
+		return false
+	}
+	item.cancel()
 
-	// Create the work item.
-	item := &workItem{
-		cancel: cancel,
-		ctx:    workCtx,
-		mux:    &sync.Mutex{},
-		wg:     g.wg,
+	// Finish the work item if no worker will.
+	if queued {
+		err := item.workError(context.Canceled)
+		item.fail(err)
+		item.finished()
+		item.report(err)
+	}
+
+	return true
+}
+
+// CancelWhere cancels every work item that has not finished and whose data matches the predicate, like Cancel does, and
+// returns how many were canceled. Use it to throw away the work for something that went away, such as a deleted
+// tenant. Work items waiting for room give up once their context is canceled. The predicate is called without holding
+// any of the Pool's locks, so it may use the Pool.
+func (g *Pool) CancelWhere(pred func(data interface{}) bool) int {
+	var canceled int
+	for _, item := range g.queue.tracked() {
+		if pred(item.data) && g.Cancel(WorkID(item.seq)) {
+			canceled++
+		}
+	}
+	return canceled
+}
+
+// PurgeQueue removes every work item that has not been taken by a worker yet, including those still waiting for room in
+// the queue, and returns how many were removed. Each one is reported to the error handler with an error matching
+// ErrPurged and its context is canceled, so Wait does not wait for it. Running work items are not affected. Use it to
+// throw away a backlog, such as during a configuration reload, while letting the work in progress finish.
+//
+// Work items waiting for room give up the next time they are woken up, so they may be finished shortly after
+// PurgeQueue returns.
+func (g *Pool) PurgeQueue() int {
+	return g.purge(false)
+}
+
+// PurgeQueueSilently is like PurgeQueue, but the removed work items are not reported to the error handler. Their
+// callbacks are still called.
+func (g *Pool) PurgeQueueSilently() int {
+	return g.purge(true)
+}
+
+// purge removes every work item that has not been taken by a worker yet and returns how many were removed.
+func (g *Pool) purge(quiet bool) int {
+	items, waiting := g.queue.purge(quiet)
+	for _, item := range items {
+		g.purged(item)
+	}
+	return len(items) + waiting
+}
+
+// purged finishes a work item removed by PurgeQueue.
+func (g *Pool) purged(item *workItem) {
+	err := item.workError(ErrPurged)
+	item.fail(err)
+	item.finished()
+	if !item.quiet {
+		item.report(err)
+	}
+}
+
+// AddWorkItemSized is like AddWorkItem, but the work item is as big as the given number of bytes, such as the size of
+// its payload. The sizes of the work items that are queued or running are limited with WithMaxQueuedBytes. A size that
+// is not positive does not count.
+func (g *Pool) AddWorkItemSized(ctx context.Context, work Work, data interface{}, bytes int64) WorkID {
+	return g.addWorkItem(ctx, &workItem{
+		size: max(bytes, 0),
+		work: work,
+		data: data,
+	})
+}
+
+// AddWorkItemLabeled is like AddWorkItem, but the work item carries the given labels, such as "tenant" or "source".
+// The labels are copied, so changing the map afterwards has no effect. They are given to the WorkError of every error
+// reported for the work item and to the function set with WithOnFinish.
+func (g *Pool) AddWorkItemLabeled(ctx context.Context, work Work, data interface{}, labels map[string]string) WorkID {
+	return g.addWorkItem(ctx, &workItem{
+		labels: maps.Clone(labels),
 		work:   work,
 		data:   data,
+	})
+}
+
+// AddPoolWorkItem is like AddWorkItem, but the Work function is given the Pool that runs it. It is meant for recursive
+// workloads, where a Work function adds follow-up work to its own Pool. Submissions made with the context given to the
+// Work function, or one derived from it, are re-entrant, so they do not block when every worker is busy.
+func (g *Pool) AddPoolWorkItem(ctx context.Context, work PoolWork, data interface{}) WorkID {
+	if work == nil {
+		g.refuseNilWork()
+		return 0
+	}
+	return g.addWorkItem(ctx, &workItem{
+		work: func(workCtx context.Context, data interface{}) error {
+			return work(workCtx, g, data)
+		},
+		data: data,
+	})
+}
+
+// AddWorkItemWithHandler is like AddWorkItem, but errors for the work item are given to the given error handler instead
+// of the Pool's. It is for the occasional work item that needs its own error handling. If the handler is nil, the
+// Pool's error handler is used.
+func (g *Pool) AddWorkItemWithHandler(ctx context.Context, work Work, data interface{}, handler ErrorHandler) WorkID {
+	return g.addWorkItem(ctx, &workItem{
+		handler: handler,
+		work:    work,
+		data:    data,
+	})
+}
+
+// AddErrFunc is like AddWorkItem for a function that takes no context or data. The function can not be told to end
+// early, so a worker waiting on it moves on when the context ends, but the function keeps running until it returns. Its
+// error is reported to the error handler as usual. A nil function is refused like a nil Work function is by
+// AddWorkItem.
+//
+// For jobs that have no use for a context, the context may be nil, in which case it is created by the Pool's context
+// factory, which is set with WithContextFactory. Killing the Pool still stops workers from taking more functions.
+func (g *Pool) AddErrFunc(ctx context.Context, fn func() error) WorkID {
+	if fn == nil {
+		g.refuseNilWork()
+		return 0
 	}
+	return g.addWorkItem(ctx, &workItem{
+		work: func(workCtx context.Context, data interface{}) error {
+			return fn()
+		},
+	})
+}
 
-	g.sendWorkItem(workCtx, item) // This will block if no worker is ready and the work item buffer is full.
+// AddFunc is like AddErrFunc for a function that can not fail.
+func (g *Pool) AddFunc(ctx context.Context, fn func()) WorkID {
+	if fn == nil {
+		g.refuseNilWork()
+		return 0
+	}
+	return g.AddErrFunc(ctx, func() error {
+		fn()
+		return nil
+	})
+}
+
+// Go gives the function to a worker like AddWorkItem, so code reads like golang.org/x/sync/errgroup. It is accepted
+// under the same rules as AddWorkItem and its error is reported to the error handler as usual. Unlike AddWorkItem, it
+// returns an error when the function was not accepted: ErrNilWork if it is nil, or the reason the Pool died if it was
+// dead. Nothing is reported to the error handler in those cases.
+func (g *Pool) Go(ctx context.Context, fn func(ctx context.Context) error) error {
+	if fn == nil {
+		return ErrNilWork
+	}
+	id := g.addWorkItem(ctx, &workItem{
+		work: func(workCtx context.Context, data interface{}) error {
+			return fn(workCtx)
+		},
+	})
+	if id == 0 {
+		return g.killCause()
+	}
+	return nil
+}
+
+// AddWorkItemContext is like AddWorkItem, but it never blocks. It tries to queue the work item once and returns nil if
+// it was accepted. Otherwise, the work item is not accepted, nothing is reported to the error handler, and the reason
+// is returned: ErrNilWork if the Work function is nil, an error matching ErrCantDo if the context has already ended,
+// the reason the Pool died if it is dead, or ErrQueueFull if no worker is ready and there is no room in the queue or
+// overflow buffer right away. Work items that are held back by ReconfigureAndDrain or that would wait in line in FIFO
+// mode are refused with ErrQueueFull too.
+//
+// It is the recommended way to add work items for producers that can not afford to wait, since the caller decides what
+// to do with a refused work item, such as shedding it or trying again later. If the context is nil, it is created by
+// the Pool's context factory.
+func (g *Pool) AddWorkItemContext(ctx context.Context, work Work, data interface{}) error {
+
+	// Refuse a nil Work function.
+	if work == nil {
+		return ErrNilWork
+	}
+
+	// Create the context if needed.
+	item := &workItem{
+		work: work,
+		data: data,
+	}
+	if ctx == nil {
+		ctx, item.release = g.cfg.contextFactory()
+	}
+
+	// Refuse the work item if the context has ended or the Pool is dead.
+	var err error
+	switch {
+	case expired(ctx) != nil:
+		err = g.cantDo(ctx, 0)
+	case g.Dead():
+		err = g.killCause()
+	}
+	if err != nil {
+		if item.release != nil {
+			item.release()
+		}
+		return err
+	}
+
+	// Try to queue the work item once.
+	reentrant := g.accept(ctx, item)
+	result, evicted, _ := g.queue.push(item, reentrant)
+	if g.queued(result, item, evicted) {
+		atomic.AddUint64(&g.stats.submitted, 1)
+		return nil
+	}
+	g.unaccept(item)
+	err = ErrQueueFull
+	if result == pushDead {
+		err = g.killCause()
+	} else {
+		atomic.AddUint64(&g.stats.rejected, 1)
+	}
+	g.emit(Event{
+		Err:  err,
+		ID:   WorkID(item.seq),
+		Type: ItemRejected,
+	})
+
+	return err
 }
 
 // Dead determines if the pool is dead.
-func (g Pool) Dead() bool {
+func (g *Pool) Dead() bool {
 	return dead(g.death)
 }
 
 // Done mimics the functionality of the context.Context Done method. It returns a channel that will close when all
 // given work has been completed or when the pool dies.
-func (g Pool) Done() <-chan struct{} {
+func (g *Pool) Done() <-chan struct{} {
 
 	// Make a channel to close.
 	c := make(chan struct{})
 
 	// Launch a goroutine that will close the channel when all work has been completed or the pool dies.
-	go g.mimic(c)
+	g.spawn(func() {
+		g.mimic(c)
+	})
 
 	return c
 }
 
-// Kill tells all the worker goroutines and work items to end.
-func (g Pool) Kill() {
-	close(g.death)
+// Kill tells all the worker goroutines and work items to end. Once Kill returns, no work item will start. Work items
+// that already started are told to end through their context. Work items that were queued, but never started, are
+// abandoned. Each one is reported to the error handler with ErrPoolKilled and no longer counted by Wait. It is safe to
+// call Kill more than once.
+//
+// With WithDrainOnKill, Kill instead stops the Pool from accepting work items and returns right away. The queued work
+// items are still run and the Pool dies once all of them are done.
+func (g *Pool) Kill() {
+	if g.cfg.drainOnKill {
+		g.queue.close()
+		g.spawn(func() {
+			g.KillWithPolicy(KillPolicy{})
+		})
+		return
+	}
+	g.kill(ErrPoolKilled)
+}
+
+// RecycleWorkers replaces every worker with a new one, one at a time. Each worker retires after it finishes its current
+// work item and runs its teardown hook. Its replacement runs the init hook and is ready before the next worker is
+// retired, so there is never more than 1 worker missing. Use it to refresh per-worker state, such as connections, that
+// has gone stale. It returns when every worker has been replaced or when the context expires, in which case the
+// context's error is returned and the roll stops after the current worker. If the Pool dies, ErrPoolKilled is returned.
+func (g *Pool) RecycleWorkers(ctx context.Context) error {
+	g.recycle.Lock()
+
+	for i := range g.workers {
+		g.workerMux.Lock()
+		old := g.workers[i]
+		g.workerMux.Unlock()
+
+		// Retire the worker and replace it once it has returned.
+		g.queue.retire(old.retire)
+		replaced := make(chan struct{})
+		g.spawn(func() {
+			<-old.exited
+			if !g.Dead() {
+				replacement := g.startWorker(old.id)
+				g.workerMux.Lock()
+				g.workers[i] = replacement
+				g.workerMux.Unlock()
+			}
+			close(replaced)
+		})
+
+		// Wait for the worker to be replaced. If the context expires first, the replacement is still started so the
+		// Pool does not lose a worker, and the lock is held until then.
+		select {
+		case <-ctx.Done():
+			g.spawn(func() {
+				<-replaced
+				g.recycle.Unlock()
+			})
+			return ctx.Err()
+		case <-replaced:
+		}
+		if g.Dead() {
+			g.recycle.Unlock()
+			return ErrPoolKilled
+		}
+
+		// Wait for the replacement to be ready.
+		g.workerMux.Lock()
+		replacement := g.workers[i]
+		g.workerMux.Unlock()
+		select {
+		case <-ctx.Done():
+			g.recycle.Unlock()
+			return ctx.Err()
+		case <-replacement.ready:
+		}
+	}
+
+	g.recycle.Unlock()
+
+	return nil
+}
+
+// KillCause is like Kill, but records the given cause as the reason the Pool died. It kills the Pool right away even
+// with WithDrainOnKill. The cause is reported for the work items that were dropped and returned by the Err method of
+// the context from AsContext. If the cause does not match ErrPoolKilled with errors.Is, it is wrapped so it does. Only
+// the first cause is kept if the Pool is killed more than once.
+func (g *Pool) KillCause(cause error) {
+	if !errors.Is(cause, ErrPoolKilled) {
+		cause = fmt.Errorf("%w: %w", ErrPoolKilled, cause)
+	}
+	g.kill(cause)
+}
+
+// Stats returns a snapshot of the Pool's counters.
+func (g *Pool) Stats() Stats {
+	stats := g.stats.snapshot()
+	stats.RetryBudget = g.budget.level()
+	if g.budget != nil {
+		stats.RetryThrottled = atomic.LoadUint64(&g.budget.throttled)
+	}
+	g.queue.mux.Lock()
+	stats.MaxPending = g.queue.peak
+	stats.OverflowFull = g.queue.filled
+	stats.Queued = len(g.queue.items)
+	stats.QueuedBytes = g.queue.bytes
+	stats.Running = len(g.queue.running)
+	g.queue.mux.Unlock()
+	return stats
+}
+
+// ResetStats clears the high-water marks in Stats, so they only cover what happens from now on. MaxPending starts over
+// from the number of work items queued now. Counters are not affected.
+func (g *Pool) ResetStats() {
+	g.queue.mux.Lock()
+	g.queue.peak = len(g.queue.items)
+	g.queue.mux.Unlock()
+}
+
+// SubmittedTotal returns the number of work items the Pool has accepted since it was created. It only ever grows, so
+// the difference between two readings is how many work items were accepted in between. Work items moved with
+// TransferPendingTo count towards both Pools, but are only completed by the one that ran them.
+func (g *Pool) SubmittedTotal() uint64 {
+	return atomic.LoadUint64(&g.stats.submitted)
+}
+
+// CompletedTotal returns the number of work items that have finished since the Pool was created, whether they
+// succeeded, failed, or were dropped. It only ever grows, so the difference between two readings is how many work
+// items finished in between.
+func (g *Pool) CompletedTotal() uint64 {
+	return atomic.LoadUint64(&g.cseq)
+}
+
+// PerWorkerStats returns a snapshot of the counters kept for each worker, in the order of the workers' numbers. Use it
+// to spot skew, such as a worker that does most of the work or a stuck worker that does none. Each worker's counters
+// are consistent with each other, but the workers are read one after the other.
+func (g *Pool) PerWorkerStats() []WorkerStats {
+	snapshot := make([]WorkerStats, len(g.workerStats))
+	for i := range g.workerStats {
+		snapshot[i] = g.workerStats[i].snapshot(uint(i) + 1)
+	}
+	return snapshot
 }
 
 // Wait mimics the functionality of the sync.WaitGroup Wait method. It returns when all given work has been completed or
 // when the pool dies.
-func (g Pool) Wait() {
+func (g *Pool) Wait() {
 	g.mimic(nil)
 }
 
-// handleErrors is meant to be a goroutine that will handle all errors returned from work items. It takes in an error
-// handler function and an async boolean. If the async boolean is true, all errors returned from work items will be
-// handled in their own goroutine.
-func (g Pool) handleErrors(handler ErrorHandler) {
+// Started returns a channel that is closed once every worker the Pool was created with has run its init hook, if any,
+// and is ready to take work items, whether or not the init hook failed. Replacement workers are not waited for. See
+// WarmUp to also learn which workers failed to get ready.
+func (g *Pool) Started() <-chan struct{} {
+	return g.started
+}
+
+// WarmUp blocks until every worker has run its init hook, which is set with WithWorkerInit, so the first work items do
+// not wait for workers to get ready. It returns early with the context's error if the context expires or with
+// ErrPoolKilled if the Pool dies. If any worker's init hook failed, the returned error joins a WorkerError for each of
+// them.
+func (g *Pool) WarmUp(ctx context.Context) error {
+	g.workerMux.Lock()
+	workers := slices.Clone(g.workers)
+	g.workerMux.Unlock()
+
+	// Wait for each worker to be ready and collect its error.
+	var errs []error
+	for _, w := range workers {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.death:
+			return ErrPoolKilled
+		case <-w.ready:
+		}
+		if w.initErr != nil {
+			errs = append(errs, &WorkerError{
+				Err:    w.initErr,
+				Worker: w.id,
+			})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// addWorkItem fills in the rest of the given work item and gives it to a worker. The given work item must have its
+// Work function, data, and any hooks set. The work item's ID is returned, or zero if the Pool was dead.
+func (g *Pool) addWorkItem(ctx context.Context, item *workItem) WorkID {
+
+	// Refuse a nil Work function before accepting anything, so the mistake is attributed to the caller.
+	if item.work == nil {
+		g.refuseNilWork()
+		return 0
+	}
+
+	// Treat a nil context like Submit does, instead of panicking deep inside the context package.
+	if ctx == nil {
+		var release context.CancelFunc
+		ctx, release = g.cfg.contextFactory()
+		item.release = chain(item.release, release)
+	}
+
+	// Check to make sure the pool isn't dead on arrival.
+	if g.Dead() {
+		if item.release != nil {
+			item.release()
+		}
+		item.handle.enter(StateDropped, g.cfg.clock.Now())
+		if item.onFinish != nil {
+			item.onFinish(g.killCause())
+		}
+		return 0
+	}
+
+	// Accept the work item.
+	reentrant := g.accept(ctx, item)
+	atomic.AddUint64(&g.stats.submitted, 1)
+
+	g.sendWorkItem(item.ctx, item, reentrant) // This will block if no worker is ready and the work is not re-entrant.
+
+	return WorkID(item.seq)
+}
+
+// accept fills in the work item and starts tracking it. It returns true if the work item is being added from a Work
+// function of this pool.
+func (g *Pool) accept(ctx context.Context, item *workItem) (reentrant bool) {
+
+	// Increment the wait pool.
+	atomic.AddInt64(&g.pending, 1)
+	g.wg.Add(1)
+
+	// Determine if this work item is being added from a Work function of this pool.
+	reentrant = g.queue.reentrant(ctx)
+
+	// Create a cancellable context that identifies this pool to any work items added from within the Work function. It
+	// is also canceled with the reason the Pool died, if it did, so a Work function only needs to watch its own
+	// context.
+	workCtx, cancel := context.WithCancelCause(ctx)
+	workCtx = context.WithValue(workCtx, workerKey{}, item)
+	if g.base != nil {
+		workCtx = baseContext{
+			Context: workCtx,
+			base:    g.base,
+		}
+	}
+	stop := context.AfterFunc(g.ctx, func() {
+		cancel(context.Cause(g.ctx))
+	})
+
+	// Fill in the work item. The sequence number and time record the order work items arrived in.
+	item.abort = func(cause error) {
+		stop()
+		cancel(cause)
+	}
+	item.cancel = func() {
+		stop()
+		cancel(context.Cause(g.ctx))
+	}
+	item.ctx = workCtx
+	item.mux = &sync.Mutex{}
+	item.parent = ctx
+	item.pool = g
+	item.submitted = g.cfg.clock.Now()
+	if reentrant && g.cfg.deadlockDetection {
+		item.submitter = ctx.Value(workerKey{}).(*workItem)
+	}
+	g.queue.track(item)
+	item.handle.accept(WorkID(item.seq), item.submitted)
+	g.emit(Event{
+		ID:   WorkID(item.seq),
+		Type: ItemAccepted,
+	})
+
+	return reentrant
+}
+
+// unaccept undoes accept for a work item that was never queued, as if it had never been given to the Pool.
+func (g *Pool) unaccept(item *workItem) {
+	g.queue.leave(item)
+	item.cancel()
+	if item.release != nil {
+		item.release()
+	}
+	g.queue.forget(item)
+	if atomic.AddInt64(&g.pending, -1) == 0 {
+		g.idle()
+	}
+	g.wg.Done()
+}
+
+// cantDo creates the error for a work item whose context ended before it could be given to a worker.
+func (g *Pool) cantDo(ctx context.Context, waited time.Duration) *CantDoError {
+	deadline, _ := ctx.Deadline()
+	return &CantDoError{
+		Cause:      context.Cause(ctx),
+		Deadline:   deadline,
+		LoadFactor: g.LoadFactor(),
+		Waited:     waited,
+	}
+}
+
+// drop finishes a work item that will never run because the Pool died and reports it to the error handler.
+func (g *Pool) drop(item *workItem) {
+	err := item.workError(g.killCause())
+	item.fail(err)
+	item.finished()
+	item.report(err)
+}
+
+// expire kills the Pool with ErrMaxLifetime when the timer fires. The timer is stopped if the Pool dies first.
+func (g *Pool) expire(timer Timer) {
+	select {
+	case <-timer.C():
+		g.KillCause(ErrMaxLifetime)
+	case <-g.death:
+		timer.Stop()
+	}
+}
+
+// handle gives the error to the given error handler. A panic in the error handler is recovered, so a bug in it does not
+// take down the program or the Pool's error path. The panic is given to the function set with WithPanicHandler, if any,
+// or logged otherwise.
+func (g *Pool) handle(handler ErrorHandler, err error) {
+	atomic.AddInt64(&g.handlers, 1)
+	defer atomic.AddInt64(&g.handlers, -1)
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		stack := debug.Stack()
+		if g.cfg.panicHandler != nil {
+			g.cfg.panicHandler(g, recovered, stack)
+			return
+		}
+		log.Printf("ctxerrpool: the error handler panicked while handling %q: %v\n%s", err, recovered, stack)
+	}()
+	handler(g, err)
+}
+
+// handleErrors is meant to be a goroutine that will handle all errors returned from work items. All errors are handled
+// in their own goroutine.
+func (g *Pool) handleErrors() {
 	for {
 		select {
 
@@ -126,20 +847,77 @@ func (g Pool) handleErrors(handler ErrorHandler) {
 		// Handle the error that were not handled by work items.
 		case err := <-g.errChan:
 
-			// Check to make sure the pool isn't dead and this case was selected.
+			// Stop if the pool died, but still handle the error, since whoever reported it already let go of it.
 			if g.Dead() {
+				g.spawn(func() {
+					g.handle(g.handler, err)
+				})
+				return
+			}
+
+			// Wait for a running error handler to return if there are too many.
+			if g.handling != nil && !g.saturate() {
+				g.spawn(func() {
+					g.handle(g.handler, err)
+				})
 				return
 			}
 
 			// Handle the error async.
-			go handler(g, err)
+			g.spawn(func() {
+				if g.handling != nil {
+					defer func() {
+						<-g.handling
+					}()
+				}
+				g.handle(g.handler, err)
+			})
 		}
 	}
 }
 
+// kill records why the Pool died, stops the queue, kills the Pool, and drops the queued work items. The cause must
+// match ErrPoolKilled.
+func (g *Pool) kill(cause error) {
+
+	// Record why the Pool died and stop the queue. Only the first cause is kept.
+	items, killed := g.queue.kill(func() error {
+		g.cancel(cause)
+		return g.killCause()
+	})
+
+	// Drop all the work items that were never taken by a worker.
+	for _, item := range items {
+		g.drop(item)
+	}
+	if killed {
+		g.emit(Event{
+			Err:  g.killCause(),
+			Type: PoolKilled,
+		})
+	}
+}
+
+// refuseNilWork refuses a nil Work function given to a method that does not return an error. It panics with
+// ErrNilWork, or reports it to the error handler with WithReportNilWork.
+func (g *Pool) refuseNilWork() {
+	if !g.cfg.reportNilWork {
+		panic(ErrNilWork)
+	}
+	g.report(ErrNilWork)
+}
+
+// killCause returns the reason the Pool died. It is ErrPoolKilled unless the Pool was killed with another cause.
+func (g *Pool) killCause() error {
+	if cause := context.Cause(g.ctx); cause != nil {
+		return cause
+	}
+	return ErrPoolKilled
+}
+
 // mimic waits for all workers to be done working or for the pool to die. Close the given channel, if any, when one
 // condition occurs.
-func (g Pool) mimic(c chan struct{}) {
+func (g *Pool) mimic(c chan struct{}) {
 
 	// Close the channel, if any, after the function returns.
 	defer func() {
@@ -157,10 +935,10 @@ func (g Pool) mimic(c chan struct{}) {
 	done := make(chan struct{})
 
 	// Launch a goroutine to wait for all workers to be done.
-	go func() {
+	g.spawn(func() {
 		g.wg.Wait()
 		close(done)
-	}()
+	})
 
 	// Wait for a condition.
 	select {
@@ -169,25 +947,129 @@ func (g Pool) mimic(c chan struct{}) {
 	}
 }
 
-// sendWorkItem adds to the work item channel's buffer or send the work directly to a worker if there is no buffer.
-func (g Pool) sendWorkItem(ctx context.Context, item *workItem) {
-
-	// Make sure the context is not dead on arrival.
-	if err := expired(item.ctx); err != nil {
-		g.errChan <- ErrCantDo
-		item.finished()
-		return
+// saturate takes a slot for an error handler, waiting for one if there are too many running. It returns false if the
+// Pool died while waiting.
+func (g *Pool) saturate() bool {
+	select {
+	case g.handling <- struct{}{}:
+		return true
+	default:
 	}
+	atomic.AddUint64(&g.stats.saturated, 1)
+	select {
+	case g.handling <- struct{}{}:
+		return true
+	case <-g.death:
+		return false
+	}
+}
 
-	// Send the work or fail to do so.
+// report sends the error to the error handler. Every error must be reported through report. A plain send on the error
+// channel could block forever if it lost the race to Kill, because the error handling goroutine stops when the Pool
+// dies, so the error is handed to the error handler directly instead.
+func (g *Pool) report(err error) {
 	select {
-	case <-ctx.Done():
-		g.errChan <- ErrCantDo
-		item.finished()
-		return
+	case g.errChan <- err:
 	case <-g.death:
+		g.spawn(func() {
+			g.handle(g.handler, err)
+		})
+	}
+}
+
+// startWorker starts a worker in the slot with the given number.
+func (g *Pool) startWorker(id uint) *worker {
+	w := g.newWorker(id)
+	g.spawn(w.start)
+	return w
+}
+
+// newWorker creates a worker with the given number without starting it.
+func (g *Pool) newWorker(id uint) *worker {
+	return &worker{
+		dispatch: newDispatcher(g.cfg, g.cfg.seed+int64(id)),
+		exited:   make(chan struct{}),
+		id:       id,
+		pool:     g,
+		ready:    make(chan struct{}),
+		retire:   make(chan struct{}),
+	}
+}
+
+// sendWorkItem adds the work item to the queue once there is room for it. Re-entrant work items are added to the queue
+// right away, up to the spill limit.
+func (g *Pool) sendWorkItem(ctx context.Context, item *workItem, reentrant bool) {
+
+	// Make sure the context is not dead on arrival.
+	if expired(item.ctx) != nil {
+		err := g.cantDo(item.ctx, 0)
+		item.fail(err)
+		item.report(item.labeled(err))
 		item.finished()
 		return
-	case g.do <- item:
 	}
+
+	// Queue the work or fail to do so.
+	start := g.cfg.clock.Now()
+	for {
+		result, evicted, changed := g.queue.push(item, reentrant)
+		if g.queued(result, item, evicted) {
+			return
+		}
+		switch result {
+		case pushDead:
+			g.drop(item)
+			return
+		case pushPurged:
+			g.queue.leave(item)
+			g.purged(item)
+			return
+		case pushRejected:
+			g.queue.leave(item)
+			atomic.AddUint64(&g.stats.rejected, 1)
+			item.fail(ErrQueueFull)
+			item.report(item.labeled(ErrQueueFull))
+			item.finished()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			g.queue.leave(item)
+			err := g.cantDo(ctx, g.cfg.clock.Now().Sub(start))
+			item.fail(err)
+			item.report(item.labeled(err))
+			item.finished()
+			return
+		case <-g.death:
+			g.queue.leave(item)
+			g.drop(item)
+			return
+		case <-changed:
+		}
+	}
+}
+
+// queued updates the statistics for a work item that was queued, reports a deadlock it broke, and fails the work item
+// it evicted, if any. It returns false if the work item was not queued.
+func (g *Pool) queued(result pushResult, item, evicted *workItem) bool {
+	switch result {
+	case pushOverflowed:
+		atomic.AddUint64(&g.stats.overflowed, 1)
+	case pushSpilled:
+		atomic.AddUint64(&g.stats.spilled, 1)
+	case pushForced:
+		atomic.AddUint64(&g.stats.spilled, 1)
+		item.report(item.labeled(ErrSelfDeadlock))
+	case pushEvicted:
+		atomic.AddUint64(&g.stats.droppedOldest, 1)
+		err := evicted.workError(ErrDroppedOldest)
+		evicted.fail(err)
+		evicted.finished()
+		evicted.report(err)
+	case pushAdded:
+	default:
+		return false
+	}
+	return true
 }
@@ -4,13 +4,768 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os/exec"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
 )
 
+// TestAddErrFunc confirms that functions without a context or data are run and their errors are reported.
+func TestAddErrFunc(t *testing.T) {
+
+	// Create an error for the function to return.
+	errFunc := errors.New("func")
+
+	// Create a wait pool that waits for the error to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have the function's error.
+		if !errors.Is(err, errFunc) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a function that fails and one that can not.
+	ran := false
+	if id := pool.AddErrFunc(ctx, func() error {
+		return errFunc
+	}); id == 0 {
+		t.Errorf("The function was not accepted.")
+		t.FailNow()
+	}
+	pool.AddFunc(ctx, func() {
+		ran = true
+	})
+
+	// Give the pool a function without a context.
+	ranWithout := false
+	if id := pool.AddFunc(nil, func() {
+		ranWithout = true
+	}); id == 0 {
+		t.Errorf("The function without a context was not accepted.")
+		t.FailNow()
+	}
+	pool.Wait()
+	wg.Wait()
+
+	// Confirm the functions that can not fail ran.
+	if !ran || !ranWithout {
+		t.Errorf("The function did not run.")
+		t.FailNow()
+	}
+
+	// Confirm a dead pool does not accept functions.
+	pool.Kill()
+	if id := pool.AddFunc(ctx, func() {}); id != 0 {
+		t.Errorf("A dead pool accepted a function.")
+		t.FailNow()
+	}
+}
+
+// TestAddPoolWorkItem confirms that work items added with AddPoolWorkItem are given their pool and can use it to add
+// follow-up work recursively.
+func TestAddPoolWorkItem(t *testing.T) {
+
+	// Wait for errors if they are in the process of being handled.
+	wg := &sync.WaitGroup{}
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		wg.Add(1)
+		defer wg.Done()
+
+		// This test case should have no error.
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Count the work that was done.
+	var count int64
+
+	// Create work that adds 2 children to the pool it is given until the given depth is reached.
+	var work ctxerrpool.PoolWork
+	work = func(workCtx context.Context, p *ctxerrpool.Pool, data interface{}) error {
+		if p != pool {
+			t.Errorf("The work item was given the wrong pool.")
+		}
+		atomic.AddInt64(&count, 1)
+		if depth := data.(int); depth > 0 {
+			for i := 0; i < 2; i++ {
+				p.AddPoolWorkItem(context.WithoutCancel(workCtx), work, depth-1)
+			}
+		}
+		return nil
+	}
+	pool.AddPoolWorkItem(ctx, work, 3)
+
+	// Wait for the worker pool.
+	select {
+	case <-pool.Done():
+	case <-ctx.Done():
+		t.Error("The recursive work deadlocked.")
+		t.FailNow()
+	}
+
+	// Confirm the whole tree of work ran.
+	if count := atomic.LoadInt64(&count); count != 15 {
+		t.Errorf("Expected 15 work items to run, but %d did.", count)
+		t.FailNow()
+	}
+
+	wg.Wait()
+}
+
+// TestAddSerial confirms that work items in a serial lane run one at a time and in order, while other lanes run
+// alongside them.
+func TestAddSerial(t *testing.T) {
+
+	// Create a worker pool with 4 workers.
+	pool := ctxerrpool.New(4, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithQueueSize(20))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Hold a work item in another lane until the gate is closed.
+	gate := make(chan struct{})
+	pool.AddSerial(ctx, "other", func(workCtx context.Context, data interface{}) error {
+		<-gate
+		return nil
+	}, nil)
+
+	// Give the pool work items in one lane and record their order and how many ran at once.
+	mux := &sync.Mutex{}
+	var order []int
+	var running, most int32
+	for i := 0; i < 10; i++ {
+		pool.AddSerial(ctx, "lane", func(workCtx context.Context, data interface{}) error {
+			now := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&most)
+				if now <= old || atomic.CompareAndSwapInt32(&most, old, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			mux.Lock()
+			order = append(order, data.(int))
+			mux.Unlock()
+			atomic.AddInt32(&running, -1)
+			return nil
+		}, i)
+	}
+
+	// Confirm the lane finishes while the other lane is still held.
+	waitFor(t, func() bool {
+		depths := pool.LaneDepths()
+		return len(depths) == 1 && depths["other"] == 1
+	})
+	mux.Lock()
+	for i, data := range order {
+		if i != data {
+			t.Errorf("The work items in the lane ran out of order: %v.", order)
+			t.FailNow()
+		}
+	}
+	mux.Unlock()
+	if most := atomic.LoadInt32(&most); most != 1 {
+		t.Errorf("Expected 1 work item in the lane at a time, but %d ran at once.", most)
+		t.FailNow()
+	}
+
+	// Let the other lane finish and confirm the lanes are removed.
+	close(gate)
+	pool.Wait()
+	if depths := pool.LaneDepths(); len(depths) != 0 {
+		t.Errorf("Expected no lanes to be left, but got %v.", depths)
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemContext confirms that AddWorkItemContext returns the reason a work item was refused without blocking
+// and without reporting it to the error handler.
+func TestAddWorkItemContext(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 1 queued work item.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	if err := pool.AddWorkItemContext(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, nil); err != nil {
+		t.Errorf("The work item was not accepted. Error: %v", err)
+		t.FailNow()
+	}
+	<-started
+
+	// Fill the queue, then confirm the next work item is refused because the queue is full.
+	noop := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	if err := pool.AddWorkItemContext(ctx, noop, nil); err != nil {
+		t.Errorf("The work item was not queued. Error: %v", err)
+		t.FailNow()
+	}
+	if err := pool.AddWorkItemContext(ctx, noop, nil); !errors.Is(err, ctxerrpool.ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm an ended context and a nil Work function are refused.
+	ended, end := context.WithCancel(context.Background())
+	end()
+	if err := pool.AddWorkItemContext(ended, noop, nil); !errors.Is(err, ctxerrpool.ErrCantDo) {
+		t.Errorf("Expected ErrCantDo. Error: %v", err)
+		t.FailNow()
+	}
+	if err := pool.AddWorkItemContext(ctx, nil, nil); !errors.Is(err, ctxerrpool.ErrNilWork) {
+		t.Errorf("Expected ErrNilWork. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Let the accepted work items finish and confirm the refused one was not waited for.
+	close(gate)
+	pool.Wait()
+	if stats := pool.Stats(); stats.Rejected != 1 {
+		t.Errorf("Expected 1 rejected work item, got %d.", stats.Rejected)
+		t.FailNow()
+	}
+
+	// Confirm a dead pool refuses work items.
+	pool.Kill()
+	if err := pool.AddWorkItemContext(ctx, noop, nil); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected ErrPoolKilled. Error: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemDeadline confirms that the deadline given with a work item ends its context, but never extends the
+// deadline of the given context.
+func TestAddWorkItemDeadline(t *testing.T) {
+
+	// Create a wait pool that waits for both work items to fail.
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have the context.DeadlineExceeded error.
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	parentDeadline, _ := ctx.Deadline()
+
+	// Record the deadline each work item's context has, then wait for it to end.
+	mux := &sync.Mutex{}
+	recorded := &sync.WaitGroup{}
+	recorded.Add(2)
+	deadlines := make([]time.Time, 2)
+	work := func(workCtx context.Context, data interface{}) error {
+		mux.Lock()
+		deadlines[data.(int)], _ = workCtx.Deadline()
+		mux.Unlock()
+		recorded.Done()
+		<-workCtx.Done()
+		return workCtx.Err()
+	}
+
+	// Give the pool a work item with a tighter deadline and one with a looser deadline.
+	tight := time.Now().Add(30 * time.Millisecond)
+	pool.AddWorkItemDeadline(ctx, tight, work, 0)
+	pool.AddWorkItemDeadline(ctx, parentDeadline.Add(time.Hour), work, 1)
+	pool.Wait()
+	wg.Wait()
+	recorded.Wait()
+
+	// Confirm only the tighter deadline was applied.
+	mux.Lock()
+	defer mux.Unlock()
+	if !deadlines[0].Equal(tight) || !deadlines[1].Equal(parentDeadline) {
+		t.Errorf("The deadlines are wrong.\nTight: %s\nLoose: %s", deadlines[0], deadlines[1])
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemID confirms that AddWorkItem returns the ID of the work item that is later given to the hooks.
+func TestAddWorkItemID(t *testing.T) {
+
+	// Record the ID of each work item as it finishes.
+	mux := &sync.Mutex{}
+	finished := make(map[interface{}]ctxerrpool.WorkID)
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithOnFinish(func(info ctxerrpool.FinishInfo) {
+		mux.Lock()
+		defer mux.Unlock()
+		finished[info.Data] = ctxerrpool.WorkID(info.SubmitSeq)
+	}))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool some work and keep the IDs.
+	ids := make(map[interface{}]ctxerrpool.WorkID)
+	for i := 0; i < 5; i++ {
+		ids[i] = pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return nil
+		}, i)
+		if ids[i] != ctxerrpool.WorkID(i+1) {
+			t.Errorf("Expected work item %d to have ID %d, got %d.", i, i+1, ids[i])
+			t.FailNow()
+		}
+	}
+	pool.Wait()
+
+	// Confirm the IDs match the ones given to the hooks.
+	mux.Lock()
+	defer mux.Unlock()
+	for data, id := range ids {
+		if finished[data] != id {
+			t.Errorf("Work item %v finished with ID %d, but was given ID %d.", data, finished[data], id)
+			t.FailNow()
+		}
+	}
+
+	// Confirm a dead pool gives no ID.
+	pool.Kill()
+	if id := pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, "dead"); id != 0 {
+		t.Errorf("Expected no ID from a dead pool, got %d.", id)
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemLabeled confirms that the labels of a work item reach the error handler and the onFinish function.
+func TestAddWorkItemLabeled(t *testing.T) {
+
+	// Create a wait pool that waits for the error to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Keep the labels given to the onFinish function.
+	finished := make(chan map[string]string, 1)
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have a WorkError with the labels.
+		var workErr *ctxerrpool.WorkError
+		if !errors.As(err, &workErr) || workErr.Labels["tenant"] != "acme" || workErr.Err.Error() != "test" {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithOnFinish(func(info ctxerrpool.FinishInfo) {
+		finished <- info.Labels
+	}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a labeled work item that fails, then change the labels.
+	labels := map[string]string{"tenant": "acme"}
+	pool.AddWorkItemLabeled(ctx, func(workCtx context.Context, data interface{}) error {
+		return errors.New("test")
+	}, "test", labels)
+	labels["tenant"] = "changed"
+
+	// Wait for the worker pool and error. The labels should not have changed.
+	pool.Wait()
+	wg.Wait()
+	if got := <-finished; got["tenant"] != "acme" {
+		t.Errorf("Unexpected labels: %v.", got)
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemNil confirms that a nil Work function is refused at the call site without being accepted and that the
+// pool keeps working.
+func TestAddWorkItemNil(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Confirm AddWorkItem panics with ctxerrpool.ErrNilWork.
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != ctxerrpool.ErrNilWork {
+				t.Errorf("Expected a panic with ctxerrpool.ErrNilWork, got %v.", recovered)
+			}
+		}()
+		pool.AddWorkItem(ctx, nil, nil)
+	}()
+
+	// Confirm Submit returns ctxerrpool.ErrNilWork.
+	if _, err := pool.Submit(ctx, nil); !errors.Is(err, ctxerrpool.ErrNilWork) {
+		t.Errorf("Expected ctxerrpool.ErrNilWork, got %v.", err)
+		t.FailNow()
+	}
+
+	// Confirm the nil Work function was never accepted and the pool still works.
+	pool.Wait()
+	done := false
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		done = true
+		return nil
+	}, nil)
+	pool.Wait()
+	if !done {
+		t.Errorf("The pool did not work after a nil Work function was refused.")
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemNilContext confirms that a nil context is treated as one from the context factory.
+func TestAddWorkItemNilContext(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	})
+	defer pool.Kill()
+
+	// Give the pool a work item with a nil context.
+	var workCtx context.Context
+	pool.AddWorkItem(nil, func(ctx context.Context, data interface{}) error {
+		workCtx = ctx
+		return nil
+	}, nil)
+	pool.Wait()
+
+	// Confirm the work item ran with a context.
+	if workCtx == nil {
+		t.Errorf("The work item did not run with a context.")
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemSized confirms that a work item waits while the sizes of the queued and running work items would go
+// over the limit and that the sizes are released when work items finish.
+func TestAddWorkItemSized(t *testing.T) {
+
+	// Create a worker pool with 2 workers and room for 100 bytes.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithQueueSize(10), ctxerrpool.WithMaxQueuedBytes(100))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep a big work item running until the gate is closed.
+	gate := make(chan struct{})
+	pool.AddWorkItemSized(ctx, func(workCtx context.Context, data interface{}) error {
+		<-gate
+		return nil
+	}, nil, 60)
+	if queued := pool.Stats().QueuedBytes; queued != 60 {
+		t.Errorf("Expected 60 queued bytes, but got %d.", queued)
+		t.FailNow()
+	}
+
+	// Add another big work item, which has to wait even though a worker is idle.
+	var ran int32
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		pool.AddWorkItemSized(ctx, func(workCtx context.Context, data interface{}) error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		}, nil, 60)
+	}()
+	waitFor(t, func() bool {
+		return pool.Blocked() == 1
+	})
+	if atomic.LoadInt32(&ran) != 0 || pool.Pending() != 0 || pool.Stats().QueuedBytes != 60 {
+		t.Errorf("The second work item was let in over the limit. Queued bytes: %d", pool.Stats().QueuedBytes)
+		t.FailNow()
+	}
+
+	// Let the first work item finish and confirm the second one runs and every byte is released.
+	close(gate)
+	<-added
+	pool.Wait()
+	if atomic.LoadInt32(&ran) != 1 || pool.Stats().QueuedBytes != 0 {
+		t.Errorf("Expected the second work item to run and the bytes to be released. Queued bytes: %d",
+			pool.Stats().QueuedBytes)
+		t.FailNow()
+	}
+}
+
+// TestAddWorkItemWithHandler confirms that errors for a work item with its own error handler go to it instead of the
+// pool's error handler.
+func TestAddWorkItemWithHandler(t *testing.T) {
+
+	// Create errors for the work items to return.
+	errOwn := errors.New("own")
+	errPool := errors.New("pool")
+
+	// Create a wait pool that waits for both errors to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	// Create a worker pool with 1 worker whose error handler should only get the pool's error.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+		if !errors.Is(err, errPool) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a work item with its own error handler and one without.
+	pool.AddWorkItemWithHandler(ctx, func(workCtx context.Context, data interface{}) error {
+		return errOwn
+	}, nil, func(p *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+		if p != pool || !errors.Is(err, errOwn) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	})
+	pool.AddWorkItemWithHandler(ctx, func(workCtx context.Context, data interface{}) error {
+		return errPool
+	}, nil, nil)
+
+	// Wait for the worker pool and errors.
+	pool.Wait()
+	wg.Wait()
+}
+
+// TestAwait confirms that the channel from Await closes once the number of work items has finished or the Pool dies.
+func TestAwait(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool 3 work items that each finish when told to.
+	next := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		go pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			select {
+			case <-next:
+			case <-workCtx.Done():
+			}
+			return nil
+		}, i)
+	}
+
+	// Confirm the channel closes after the second work item finishes, but not before.
+	done := pool.Await(2)
+	next <- struct{}{}
+	select {
+	case <-done:
+		t.Errorf("The channel closed after 1 work item finished.")
+		t.FailNow()
+	case <-time.After(20 * time.Millisecond):
+	}
+	next <- struct{}{}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Errorf("The channel did not close after 2 work items finished.")
+		t.FailNow()
+	}
+
+	// Confirm a channel for nothing is already closed and that the rest close when the pool dies.
+	select {
+	case <-pool.Await(0):
+	default:
+		t.Errorf("The channel for no work items was not closed.")
+		t.FailNow()
+	}
+	done = pool.Await(5)
+	pool.Kill()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Errorf("The channel did not close when the pool died.")
+		t.FailNow()
+	}
+
+	// Confirm the pool no longer tracks the channel.
+	if awaiting := pool.Awaiting(); awaiting != 0 {
+		t.Errorf("Expected no channels to be tracked after the pool died, but %d are.", awaiting)
+		t.FailNow()
+	}
+}
+
+// TestCancel confirms that Cancel ends a single queued or running work item.
+func TestCancel(t *testing.T) {
+
+	// Create a wait pool that waits for both work items to be reported as canceled.
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	// Create a worker pool with 1 worker and a queue.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have the context.Canceled error.
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until its context is canceled, then queue a work item that should never run.
+	started := make(chan struct{})
+	running := pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		return workCtx.Err()
+	}, "running")
+	<-started
+	queued := pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		t.Error("A canceled work item ran.")
+		t.FailNow()
+		return nil
+	}, "queued")
+
+	// Cancel both work items and an unknown one.
+	if !pool.Cancel(queued) || !pool.Cancel(running) || pool.Cancel(100) {
+		t.Error("Cancel did not find the right work items.")
+		t.FailNow()
+	}
+
+	// Wait for the worker pool and errors. Finished work items can not be canceled.
+	pool.Wait()
+	wg.Wait()
+	if pool.Cancel(running) {
+		t.Error("A finished work item was canceled.")
+		t.FailNow()
+	}
+}
+
+// TestCancelWhere confirms that CancelWhere ends the queued and running work items whose data matches and leaves the
+// rest alone.
+func TestCancelWhere(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a queue that counts the canceled work items.
+	var canceledErrs int64
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		if errors.Is(err, context.Canceled) {
+			atomic.AddInt64(&canceledErrs, 1)
+		}
+	}, ctxerrpool.WithQueueSize(2))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy with work for one tenant, then queue work for it and another tenant.
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		return workCtx.Err()
+	}, "deleted")
+	<-started
+	var ran []interface{}
+	work := func(workCtx context.Context, data interface{}) error {
+		ran = append(ran, data)
+		return nil
+	}
+	pool.AddWorkItem(ctx, work, "deleted")
+	pool.AddWorkItem(ctx, work, "kept")
+
+	// Cancel the work for the deleted tenant.
+	if canceled := pool.CancelWhere(func(data interface{}) bool {
+		return data == "deleted"
+	}); canceled != 2 {
+		t.Errorf("Expected 2 work items to be canceled, but %d were.", canceled)
+		t.FailNow()
+	}
+
+	// Confirm only the other tenant's work ran and both canceled work items were reported.
+	pool.Wait()
+	if len(ran) != 1 || ran[0] != "kept" {
+		t.Errorf("Expected only the kept work item to run, but these did: %v", ran)
+		t.FailNow()
+	}
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(&canceledErrs) == 2
+	})
+}
+
 // TestDeathBeforeWork confirms that a worker pool can be killed before doing any work safely.
 func TestDeathBeforeWork(t *testing.T) {
 
@@ -18,7 +773,7 @@ func TestDeathBeforeWork(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -35,6 +790,42 @@ func TestDeathBeforeWork(t *testing.T) {
 	wg.Wait()
 }
 
+// TestDeathCancelsContext confirms that the pool dying cancels the context given to a Work function with the reason it
+// died, and that the context error the Work function returns because of it is not reported.
+func TestDeathCancelsContext(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	})
+
+	// Create a reason for the pool to die.
+	errReason := errors.New("shutting down")
+
+	// Give the pool work that only watches its own context and record why it ended.
+	started := make(chan struct{})
+	ended := make(chan error, 1)
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		ended <- context.Cause(workCtx)
+		return workCtx.Err()
+	}, nil)
+	<-started
+
+	// Kill the pool and confirm the work ended because of it.
+	pool.KillCause(errReason)
+	if err := <-ended; !errors.Is(err, errReason) || !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected the work to end because the pool died. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Wait for the Work function to return and any error to be handled.
+	pool.Wait()
+	ctxerrpooltest.AssertNoLeaks(t, pool)
+}
+
 // TestDeathDeadOnArrival confirms that a worker pool can be killed and then given work, but no work will be performed.
 func TestDeathDeadOnArrival(t *testing.T) {
 
@@ -42,7 +833,7 @@ func TestDeathDeadOnArrival(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -77,7 +868,7 @@ func TestDeathDuring(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -136,7 +927,7 @@ func TestDone(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -184,14 +975,21 @@ func TestErrCantDo(t *testing.T) {
 	wg.Add(1)
 
 	// Create a worker pool with 0 workers.
-	pool := ctxerrpool.New(0, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(0, func(pool *ctxerrpool.Pool, err error) {
 		defer wg.Done()
 
-		// This test case should have the ctxerrpool.ErrCantDo error.
-		if !errors.Is(err, ctxerrpool.ErrCantDo) {
+		// This test case should have the ctxerrpool.ErrCantDo error caused by the context's deadline.
+		if !errors.Is(err, ctxerrpool.ErrCantDo) || errors.Unwrap(err) != context.DeadlineExceeded {
 			t.Errorf("An error occurred. Error: %v", err)
 			t.FailNow()
 		}
+
+		// The error should say how long the work item waited, for what deadline, and how busy the pool was.
+		var cantDo *ctxerrpool.CantDoError
+		if !errors.As(err, &cantDo) || cantDo.Deadline.IsZero() || cantDo.Waited <= 0 || cantDo.LoadFactor != 1 {
+			t.Errorf("The error is missing details. Error: %v", err)
+			t.FailNow()
+		}
 	})
 
 	// Create a context for the job.
@@ -217,11 +1015,11 @@ func TestErrCantDoDeadOnArrival(t *testing.T) {
 	wg.Add(1)
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		defer wg.Done()
 
-		// This test case should have the ctxerrpool.ErrCantDo error.
-		if !errors.Is(err, ctxerrpool.ErrCantDo) {
+		// This test case should have the ctxerrpool.ErrCantDo error caused by the context's deadline.
+		if !errors.Is(err, ctxerrpool.ErrCantDo) || !errors.Is(err, context.DeadlineExceeded) {
 			t.Errorf("An error occurred. Error: %v", err)
 			t.FailNow()
 		}
@@ -249,8 +1047,9 @@ func TestErrorTimeout(t *testing.T) {
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 
-	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	// Create a worker pool with 1 worker and a clock controlled by the test.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		defer wg.Done()
 
 		// This test case should have the context.DeadlineExceeded error.
@@ -258,24 +1057,24 @@ func TestErrorTimeout(t *testing.T) {
 			t.Errorf("An error occurred. Error: %v", err)
 			t.FailNow()
 		}
-	})
+	}, ctxerrpool.WithClock(clock))
 
 	// Create a context for the job that will time out.
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	ctx, cancel := clock.WithTimeout(context.Background(), time.Millisecond*50)
 	defer cancel()
 
-	// Get a worker to sleep for a second, but async wait for its context to expire.
+	// Get a worker to wait for its context to expire.
+	started := make(chan struct{})
 	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
-		var err error
-		select {
-		case <-time.After(time.Second):
-			t.FailNow()
-		case <-workCtx.Done(): // Good case.
-			err = workCtx.Err()
-		}
-		return err
+		close(started)
+		<-workCtx.Done()
+		return workCtx.Err()
 	}, "test")
 
+	// Let the time run out once the work has started.
+	<-started
+	clock.Advance(time.Millisecond * 50)
+
 	// Wait for the worker pool and error.
 	pool.Wait()
 	wg.Wait()
@@ -291,7 +1090,7 @@ func TestErrorTimeoutBadWork(t *testing.T) {
 	wg.Add(1)
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		defer wg.Done()
 
 		// This test case should have the context.DeadlineExceeded error.
@@ -308,17 +1107,63 @@ func TestErrorTimeoutBadWork(t *testing.T) {
 	// Get a worker to sleep for a second.
 	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
 
-		// Do not respect workCtx.
-		select {
-		case <-time.After(time.Second * 10): // Fail after the tests should be done. The goroutine for this work will leak.
+		// Do not respect workCtx.
+		select {
+		case <-time.After(time.Second * 10): // Fail after the tests should be done. The goroutine for this work will leak.
+			t.FailNow()
+		}
+		return nil
+	}, "test")
+
+	// Wait for the worker pool and error.
+	pool.Wait()
+	wg.Wait()
+}
+
+// TestGo confirms that Go runs the function with its error reported to the error handler and returns why it was not
+// accepted.
+func TestGo(t *testing.T) {
+
+	// Create a wait pool that waits for the error to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have the error of the function.
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("An error occurred. Error: %v", err)
 			t.FailNow()
 		}
-		return nil
-	}, "test")
+	})
 
-	// Wait for the worker pool and error.
-	pool.Wait()
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a function that fails.
+	if err := pool.Go(ctx, func(ctx context.Context) error {
+		return io.EOF
+	}); err != nil {
+		t.Errorf("The function was not accepted. Error: %v", err)
+		t.FailNow()
+	}
 	wg.Wait()
+
+	// Confirm a nil function and a dead pool are refused.
+	if err := pool.Go(ctx, nil); !errors.Is(err, ctxerrpool.ErrNilWork) {
+		t.Errorf("Expected ErrNilWork. Error: %v", err)
+		t.FailNow()
+	}
+	pool.Kill()
+	if err := pool.Go(ctx, func(ctx context.Context) error {
+		return nil
+	}); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected ErrPoolKilled. Error: %v", err)
+		t.FailNow()
+	}
 }
 
 // TestKill confirms that the Kill method behaves as expected.
@@ -328,7 +1173,7 @@ func TestKill(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -374,6 +1219,161 @@ func TestKill(t *testing.T) {
 	wg.Wait()
 }
 
+// TestKillNoStart confirms that work items that were queued, but not started, never start after Kill returns.
+func TestKillNoStart(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a queue.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(10))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the pool is killed.
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		return nil
+	}, "busy")
+	<-started
+
+	// Queue work items with a sentinel that should never be reached.
+	mux := &sync.Mutex{}
+	reached := false
+	for i := 0; i < 10; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			mux.Lock()
+			defer mux.Unlock()
+			reached = true
+			return nil
+		}, i)
+	}
+
+	// Kill the pool and give the worker a chance to misbehave.
+	pool.Kill()
+	time.Sleep(time.Millisecond * 50)
+
+	// Confirm the sentinel was never reached.
+	mux.Lock()
+	defer mux.Unlock()
+	if reached {
+		t.Error("A work item started after the pool was killed.")
+		t.FailNow()
+	}
+}
+
+// TestKillReportsDropped confirms that work items dropped because the pool died are reported to the error handler.
+func TestKillReportsDropped(t *testing.T) {
+
+	// Create a wait pool that waits for the dropped work items to be reported.
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+
+	// Keep track of the data of the dropped work items and a mutex for it.
+	mux := &sync.Mutex{}
+	dropped := make(map[interface{}]bool)
+
+	// Create a worker pool with 1 worker and a queue.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+
+		// Ignore the error from the busy work item.
+		var workErr *ctxerrpool.WorkError
+		if !errors.As(err, &workErr) {
+			return
+		}
+		defer wg.Done()
+
+		// This test case should have the ctxerrpool.ErrPoolKilled error.
+		if !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+
+		mux.Lock()
+		defer mux.Unlock()
+		dropped[workErr.Data] = true
+	}, ctxerrpool.WithQueueSize(5))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the pool is killed.
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-workCtx.Done()
+		return nil
+	}, "busy")
+	<-started
+
+	// Queue work items that will never run.
+	for i := 0; i < 5; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			return nil
+		}, i)
+	}
+
+	// Kill the pool and wait for the dropped work items to be reported.
+	pool.Kill()
+	wg.Wait()
+
+	// Confirm every queued work item was reported.
+	mux.Lock()
+	defer mux.Unlock()
+	for i := 0; i < 5; i++ {
+		if !dropped[i] {
+			t.Errorf("Work item %d was not reported as dropped.", i)
+			t.FailNow()
+		}
+	}
+}
+
+// TestKillWhileReporting confirms that nothing blocks forever when errors are reported at the same moment the pool is
+// killed. Run it with -race.
+func TestKillWhileReporting(t *testing.T) {
+	for i := 0; i < 200; i++ {
+
+		// Create a worker pool with 1 worker that rejects work items when it is busy.
+		pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {},
+			ctxerrpool.WithRejectionPolicy(ctxerrpool.Reject))
+
+		// Create a context.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+		// Get the worker to return an error once the gate is closed.
+		gate := make(chan struct{})
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			<-gate
+			return errors.New("test")
+		}, "test")
+
+		// Report errors from a worker and from a rejected work item while the pool is killed.
+		rejected := make(chan struct{})
+		go func() {
+			defer close(rejected)
+			pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+				return nil
+			}, "rejected")
+		}()
+		close(gate)
+
+		// Confirm the work and the rejected work item do not block on reporting their errors.
+		if err := pool.KillAndWait(ctx); err != nil {
+			t.Errorf("The work blocked while reporting its error. Error: %v", err)
+			t.FailNow()
+		}
+		select {
+		case <-rejected:
+		case <-ctx.Done():
+			t.Error("The rejected work item blocked while reporting its error.")
+			t.FailNow()
+		}
+		cancel()
+	}
+}
+
 // TestMultiWorker confirms multi worker pools will work as expected.
 func TestMultiWorker(t *testing.T) {
 
@@ -381,7 +1381,7 @@ func TestMultiWorker(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 2 workers.
-	pool := ctxerrpool.New(2, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -430,7 +1430,7 @@ func TestNew(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -453,6 +1453,410 @@ func TestNew(t *testing.T) {
 	wg.Wait()
 }
 
+// TestNewDefault confirms that the pools sized to the machine follow GOMAXPROCS when they are created.
+func TestNewDefault(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	// Try the sizes of a container limited to 1 CPU and a bigger machine.
+	for _, procs := range []int{1, 3} {
+		runtime.GOMAXPROCS(procs)
+
+		// Create the pools.
+		handler := func(pool *ctxerrpool.Pool, err error) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+		pool := ctxerrpool.NewDefault(handler)
+		ioPool := ctxerrpool.NewIO(handler)
+
+		// Confirm their number of workers.
+		if workers := len(pool.PerWorkerStats()); workers != procs {
+			t.Errorf("Expected %d workers with GOMAXPROCS %d, but got %d.", procs, procs, workers)
+			t.FailNow()
+		}
+		if workers := len(ioPool.PerWorkerStats()); workers != procs*ctxerrpool.IOMultiplier {
+			t.Errorf("Expected %d IO workers with GOMAXPROCS %d, but got %d.", procs*ctxerrpool.IOMultiplier, procs,
+				workers)
+			t.FailNow()
+		}
+		pool.Kill()
+		ioPool.Kill()
+	}
+}
+
+// TestNoCopy confirms that go vet reports copies of a Pool.
+func TestNoCopy(t *testing.T) {
+
+	// Find the go tool.
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("The go tool is not available.")
+	}
+
+	// Vet a package that copies a Pool. It should fail.
+	out, err := exec.Command(goTool, "vet", "./testdata/copy").CombinedOutput()
+	if err == nil {
+		t.Error("go vet did not report a copied Pool.")
+		t.FailNow()
+	}
+	if !strings.Contains(string(out), "copies lock value") {
+		t.Errorf("go vet failed for an unexpected reason. Output: %s", out)
+		t.FailNow()
+	}
+}
+
+// TestOneWorkerOrder confirms that a worker pool with 1 worker and a queue starts work items in the order they were
+// given to it, even with many producers at once.
+func TestOneWorkerOrder(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 2 queued work items.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(2))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Have several producers give the pool work at once.
+	mux := sync.Mutex{}
+	var ids []ctxerrpool.WorkID
+	producers := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		producers.Add(1)
+		go func() {
+			defer producers.Done()
+			for j := 0; j < 20; j++ {
+				handle := &ctxerrpool.Handle{}
+				_, _ = pool.Submit(ctx, func(workCtx context.Context, data interface{}) error {
+					mux.Lock()
+					ids = append(ids, handle.ID())
+					mux.Unlock()
+					return nil
+				}, ctxerrpool.WithHandle(handle))
+			}
+		}()
+	}
+	producers.Wait()
+
+	// Wait for the worker pool.
+	select {
+	case <-pool.Done():
+	case <-ctx.Done():
+		t.Error("The producers got stuck.")
+		t.FailNow()
+	}
+
+	// Confirm every work item ran in the order they were given to the pool.
+	mux.Lock()
+	defer mux.Unlock()
+	if len(ids) != 160 {
+		t.Errorf("Expected 160 work items to run, but %d did.", len(ids))
+		t.FailNow()
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] < ids[i-1] {
+			t.Errorf("Work item %d ran after work item %d.", ids[i], ids[i-1])
+			t.FailNow()
+		}
+	}
+}
+
+// TestPerWorkerStats confirms that the counters of each worker add up to the work the Pool did.
+func TestPerWorkerStats(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool 4 work items that take a while, 1 of which fails.
+	for i := 0; i < 4; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			time.Sleep(5 * time.Millisecond)
+			if data == 0 {
+				return io.EOF
+			}
+			return nil
+		}, i)
+	}
+	pool.Wait()
+
+	// Confirm the counters of the workers add up.
+	stats := pool.PerWorkerStats()
+	if len(stats) != 2 {
+		t.Errorf("Expected stats for 2 workers, got %d.", len(stats))
+		t.FailNow()
+	}
+	var busy time.Duration
+	var errored, processed uint64
+	for i, worker := range stats {
+		if worker.Worker != uint(i)+1 || worker.Processed > 0 && worker.LastActive.IsZero() {
+			t.Errorf("The stats of worker %d are wrong: %+v", i+1, worker)
+			t.FailNow()
+		}
+		busy += worker.Busy
+		errored += worker.Errored
+		processed += worker.Processed
+	}
+	if processed != 4 || errored != 1 || busy < 20*time.Millisecond {
+		t.Errorf("The stats do not add up.\nProcessed: %d\nErrored: %d\nBusy: %s", processed, errored, busy)
+		t.FailNow()
+	}
+}
+
+// TestPurgeQueue confirms that queued work items and those waiting for room are purged while the running work item
+// finishes.
+func TestPurgeQueue(t *testing.T) {
+
+	// Create a wait pool that waits for the purged work items to be reported.
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	// Create a worker pool with 1 worker and room for 1 queued work item.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have purged work items.
+		if !errors.Is(err, ctxerrpool.ErrPurged) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	finished := false
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		finished = true
+		return nil
+	}, nil)
+	<-started
+
+	// Queue a work item and have another one wait for room.
+	work := func(workCtx context.Context, data interface{}) error {
+		t.Error("A purged work item ran.")
+		return nil
+	}
+	pool.AddWorkItem(ctx, work, nil)
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		pool.AddWorkItem(ctx, work, nil)
+	}()
+	waitFor(t, func() bool {
+		return pool.PendingCount() == 3
+	})
+
+	// Purge both and confirm the running work item finishes.
+	if purged := pool.PurgeQueue(); purged != 2 {
+		t.Errorf("Expected 2 purged work items, but %d were.", purged)
+		t.FailNow()
+	}
+	<-added
+	wg.Wait()
+	close(gate)
+	pool.Wait()
+	if !finished {
+		t.Error("The running work item did not finish.")
+		t.FailNow()
+	}
+
+	// Confirm purging silently does not report anything.
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	pool.PurgeQueueSilently()
+	pool.Wait()
+}
+
+// TestReentrant confirms that work items can add more work to their own pool without the go keyword, even when every
+// worker is busy doing the same.
+func TestReentrant(t *testing.T) {
+
+	// Wait for errors if they are in the process of being handled.
+	wg := &sync.WaitGroup{}
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		wg.Add(1)
+		defer wg.Done()
+
+		// This test case should have no error.
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Count the work that was done and keep a mutex for it.
+	mux := &sync.Mutex{}
+	children := 0
+
+	// Have every worker add more work to the pool synchronously while all workers are busy.
+	for i := 0; i < 2; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			for j := 0; j < 2; j++ {
+				pool.AddWorkItem(context.WithoutCancel(workCtx), func(workCtx context.Context, data interface{}) error {
+					mux.Lock()
+					defer mux.Unlock()
+					children++
+					return nil
+				}, "child")
+			}
+			return nil
+		}, "parent")
+	}
+
+	// Wait for the worker pool.
+	select {
+	case <-pool.Done():
+	case <-ctx.Done():
+		t.Error("The re-entrant work deadlocked.")
+		t.FailNow()
+	}
+
+	// Confirm all the children ran.
+	mux.Lock()
+	defer mux.Unlock()
+	if children != 4 {
+		t.Errorf("Expected 4 children to run, but %d did.", children)
+		t.FailNow()
+	}
+
+	wg.Wait()
+}
+
+// TestResetStats confirms that MaxPending records the deepest the queue got and that ResetStats starts it over.
+func TestResetStats(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 3 queued work items, and make the worker busy.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(3))
+	defer pool.Kill()
+	<-pool.Started()
+	release := busy(pool)
+
+	// Queue 3 work items and let them all run.
+	for i := 0; i < 3; i++ {
+		pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+			return nil
+		}, nil)
+	}
+	release()
+	pool.Wait()
+
+	// Confirm the high-water mark outlived the queue.
+	if stats := pool.Stats(); stats.MaxPending != 3 || stats.Queued != 0 {
+		t.Errorf("Expected a high-water mark of 3 with an empty queue, got %d with %d queued.", stats.MaxPending,
+			stats.Queued)
+		t.FailNow()
+	}
+
+	// Reset the high-water mark and queue 1 work item.
+	pool.ResetStats()
+	if maxPending := pool.Stats().MaxPending; maxPending != 0 {
+		t.Errorf("Expected the high-water mark to be reset, got %d.", maxPending)
+		t.FailNow()
+	}
+	release = busy(pool)
+	pool.AddWorkItem(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	release()
+	pool.Wait()
+	if maxPending := pool.Stats().MaxPending; maxPending != 1 {
+		t.Errorf("Expected a high-water mark of 1, got %d.", maxPending)
+		t.FailNow()
+	}
+}
+
+// TestStarted confirms that Started is closed only once every worker has run its init hook.
+func TestStarted(t *testing.T) {
+
+	// Create a worker pool with 3 workers that can't get ready until told to.
+	gate := make(chan struct{})
+	pool := ctxerrpool.New(3, func(pool *ctxerrpool.Pool, err error) {},
+		ctxerrpool.WithWorkerInit(func(worker uint) error {
+			<-gate
+			return nil
+		}))
+	defer pool.Kill()
+
+	// Confirm the pool has not started while its workers are getting ready.
+	select {
+	case <-pool.Started():
+		t.Error("The pool started before its workers were ready.")
+		t.FailNow()
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Let the workers get ready and confirm the pool started.
+	close(gate)
+	select {
+	case <-pool.Started():
+	case <-time.After(time.Second):
+		t.Error("The pool did not start after its workers were ready.")
+		t.FailNow()
+	}
+
+	// Confirm a pool without workers has started.
+	empty := ctxerrpool.New(0, func(pool *ctxerrpool.Pool, err error) {})
+	defer empty.Kill()
+	select {
+	case <-empty.Started():
+	default:
+		t.Error("A pool without workers did not start.")
+		t.FailNow()
+	}
+}
+
+// TestSubmittedTotal confirms that SubmittedTotal and CompletedTotal count the work items the Pool accepted and
+// finished.
+func TestSubmittedTotal(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool 3 work items, 1 of which fails.
+	for i := 0; i < 3; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			if data == 0 {
+				return io.EOF
+			}
+			return nil
+		}, i)
+	}
+	pool.Wait()
+
+	// Confirm work items given to a dead pool are not counted.
+	pool.Kill()
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	if submitted, completed := pool.SubmittedTotal(), pool.CompletedTotal(); submitted != 3 || completed != 3 {
+		t.Errorf("Expected 3 submitted and completed work items.\nSubmitted: %d\nCompleted: %d", submitted,
+			completed)
+		t.FailNow()
+	}
+}
+
 // TestWait confirms the Wait method behaves as expected.
 func TestWait(t *testing.T) {
 
@@ -460,7 +1864,7 @@ func TestWait(t *testing.T) {
 	wg := &sync.WaitGroup{}
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		wg.Add(1)
 		defer wg.Done()
 
@@ -512,7 +1916,7 @@ func TestWorkerError(t *testing.T) {
 	wg.Add(1)
 
 	// Create a worker pool with 1 worker.
-	pool := ctxerrpool.New(1, func(pool ctxerrpool.Pool, err error) {
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
 		defer wg.Done()
 
 		// This test case should have no error.
@@ -0,0 +1,756 @@
+package ctxerrpool
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// workerKey is the context key used to mark the context given to a Work function with the work item it runs for, which
+// identifies the Pool that is running it.
+type workerKey struct{}
+
+// pushResult describes what happened when a work item was pushed to the queue.
+type pushResult uint8
+
+const (
+
+	// pushAdded means the work item was added to the queue.
+	pushAdded pushResult = iota
+
+	// pushOverflowed means the work item was added to the queue's overflow buffer.
+	pushOverflowed
+
+	// pushSpilled means the re-entrant work item was added to the queue beyond its size and overflow buffer.
+	pushSpilled
+
+	// pushForced means the re-entrant work item was added to the queue beyond the spill limit because every worker was
+	// waiting to add a work item. See WithDeadlockDetection.
+	pushForced
+
+	// pushEvicted means the work item was added to the queue in place of the oldest queued work item.
+	pushEvicted
+
+	// pushFull means there was no room for the work item or the queue is paused.
+	pushFull
+
+	// pushRejected means there was no room for the work item and the Reject policy applies.
+	pushRejected
+
+	// pushPurged means the work item was waiting for room when PurgeQueue was called.
+	pushPurged
+
+	// pushDead means the Pool has died or is dying and will not accept the work item.
+	pushDead
+)
+
+// queue holds the work items that have been accepted by the Pool, but have not yet been taken by a worker. It also
+// keeps track of the work items that workers have started.
+type queue struct {
+	aborting    bool
+	blocked     map[*workItem]struct{}
+	bytes       int64
+	categories  map[string]uint
+	changed     chan struct{}
+	closed      bool
+	death       chan struct{}
+	detect      bool
+	dispatch    *dispatcher
+	filled      uint64
+	fifo        bool
+	floor       uint64
+	idle        uint
+	items       []*workItem
+	lanes       map[string]*lane
+	lifo        bool
+	limits      map[string]uint
+	line        []*workItem
+	live        map[uint64]*workItem
+	maxBytes    int64
+	mux         sync.Mutex
+	ordered     bool
+	overflow    uint
+	paused      bool
+	peak        int
+	prioritized bool
+	rejection   RejectionPolicy
+	reserved    uint
+	running     map[*workItem]struct{}
+	seq         uint64
+	size        uint
+	spill       uint
+	stalled     map[*workItem]uint
+	tokens      []*FlushToken
+	unstarted   []uint64
+	workers     uint
+}
+
+// newQueue creates a new queue with the configured size and overflow buffer for the given number of workers. The queue
+// stops handing out work items when the death channel is closed by kill.
+func newQueue(cfg config, death chan struct{}, workers uint) *queue {
+	return &queue{
+		blocked:     make(map[*workItem]struct{}),
+		categories:  make(map[string]uint),
+		lanes:       make(map[string]*lane),
+		changed:     make(chan struct{}),
+		death:       death,
+		detect:      cfg.deadlockDetection,
+		dispatch:    newDispatcher(cfg, cfg.seed),
+		fifo:        cfg.fifo,
+		floor:       1,
+		lifo:        cfg.dispatchOrder == LIFO,
+		limits:      cfg.categoryLimits,
+		live:        make(map[uint64]*workItem),
+		maxBytes:    cfg.maxQueuedBytes,
+		ordered:     keepsOrder(cfg, workers),
+		overflow:    cfg.overflow,
+		prioritized: !cfg.fifoGuarantee,
+		rejection:   cfg.rejection,
+		running:     make(map[*workItem]struct{}),
+		size:        cfg.queueSize,
+		spill:       cfg.spillLimit,
+		stalled:     make(map[*workItem]uint),
+		workers:     workers,
+	}
+}
+
+// lane keeps track of the work items in a serial lane. See Pool.AddSerial.
+type lane struct {
+	depth   uint
+	running bool
+}
+
+// broadcast wakes up everything waiting on the queue to change. The lock must be held.
+func (q *queue) broadcast() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// begin determines if a worker may start a work item it has taken from the queue. If so, the work item is marked as
+// started at the given time and tracked as running until end is called, and the wait group is incremented. The caller
+// must call Done on it once the work item's goroutine returns or it decides not to start one. Doing it under the lock
+// makes sure anything that waits on the wait group after kill or drained sees every work item that began. Once kill
+// has returned, begin always returns false. abort is true once abortInFlight was called, so the caller must cancel the
+// work item as it starts.
+func (q *queue) begin(item *workItem, started time.Time, working *sync.WaitGroup) (begun, abort bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if dead(q.death) {
+		return false, false
+	}
+	item.started = started
+	q.running[item] = struct{}{}
+	working.Add(1)
+	return true, q.aborting
+}
+
+// cancel finds the work item with the given sequence number. If it is queued, it is removed from the queue and
+// queued is true. ok is false if the work item has finished or never existed.
+func (q *queue) cancel(seq uint64) (item *workItem, queued, ok bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	item, ok = q.live[seq]
+	if !ok {
+		return nil, false, false
+	}
+	for i, other := range q.items {
+		if other == item {
+			q.remove(i)
+			q.broadcast()
+			return item, true, true
+		}
+	}
+	return item, false, true
+}
+
+// close stops the queue from accepting work items without killing it. Work items already in the queue are still
+// handed out to workers.
+func (q *queue) close() {
+	q.mux.Lock()
+	q.closed = true
+	q.broadcast()
+	q.mux.Unlock()
+}
+
+// drain removes all work items from the queue and returns them.
+func (q *queue) drain() (items []*workItem) {
+	q.mux.Lock()
+	items, q.items = q.items, nil
+	q.broadcast()
+	q.mux.Unlock()
+	return items
+}
+
+// purge removes all work items from the queue and returns them. Work items waiting for room are marked as purged and
+// woken up, so they give up the next time they try to be queued. It returns how many of them were marked. Work items
+// that are quiet are not reported.
+func (q *queue) purge(quiet bool) (items []*workItem, waiting int) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	items, q.items = q.items, nil
+	for _, item := range items {
+		item.purged = true
+		item.quiet = quiet
+	}
+	for item := range q.blocked {
+		if !item.purged {
+			item.purged = true
+			item.quiet = quiet
+			waiting++
+		}
+	}
+	q.broadcast()
+	return items, waiting
+}
+
+// leave removes a work item from the line of work items waiting for room. It must be called when a work item gives up
+// waiting.
+func (q *queue) leave(item *workItem) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.unblock(item)
+	for i, waiting := range q.line {
+		if waiting == item {
+			q.line = append(q.line[:i], q.line[i+1:]...)
+			q.broadcast()
+			return
+		}
+	}
+}
+
+// end stops tracking a work item as running. It must be called once for every work item returned by pop.
+func (q *queue) end(item *workItem) {
+	q.mux.Lock()
+	delete(q.running, item)
+	if item.category != "" {
+		q.categories[item.category]--
+		q.broadcast()
+	}
+	if item.lane != "" {
+		q.lanes[item.lane].running = false
+		q.broadcast()
+	}
+	q.mux.Unlock()
+}
+
+// forget stops tracking a work item that has finished.
+func (q *queue) forget(item *workItem) {
+	q.mux.Lock()
+	if _, ok := q.live[item.seq]; ok && item.lane != "" {
+		l := q.lanes[item.lane]
+		if l.depth--; l.depth == 0 {
+			delete(q.lanes, item.lane)
+		}
+	}
+	delete(q.live, item.seq)
+	if q.started(item.seq) {
+		q.broadcast()
+	}
+	q.settle()
+	q.unblock(item)
+	if item.weighed {
+		q.bytes -= item.size
+		item.weighed = false
+	}
+	released := item.reserved
+	if released {
+		item.reserved = false
+		q.reserved--
+	}
+	if q.paused || item.size > 0 || released {
+		q.broadcast()
+	}
+	q.mux.Unlock()
+}
+
+// settle moves the floor past the work items that have finished, in the order they were given to the Pool, and
+// closes the FlushTokens whose work items have all finished. The lock must be held.
+func (q *queue) settle() {
+	for q.floor <= q.seq && q.live[q.floor] == nil {
+		q.floor++
+	}
+	for len(q.tokens) > 0 && q.tokens[0].last < q.floor {
+		close(q.tokens[0].done)
+		q.tokens[0] = nil
+		q.tokens = q.tokens[1:]
+	}
+}
+
+// checkpoint creates a FlushToken for the work items that have been given to the Pool so far. It is already closed if
+// they have all finished. The lock must be held.
+func (q *queue) checkpoint(token *FlushToken) {
+	token.last = q.seq
+	if token.last < q.floor {
+		close(token.done)
+		return
+	}
+	q.tokens = append(q.tokens, token)
+}
+
+// resolve closes every FlushToken that is still waiting with the given error. The lock must be held.
+func (q *queue) resolve(err error) {
+	for _, token := range q.tokens {
+		token.err = err
+		close(token.done)
+	}
+	q.tokens = nil
+}
+
+// drained determines if every work item the queue tracks is blocked waiting to be queued. If not, the returned channel
+// will close when it is worth checking again.
+func (q *queue) drained() (drained bool, changed <-chan struct{}) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return len(q.live) == len(q.blocked), q.changed
+}
+
+// pause makes the queue hold back work items that are not re-entrant until resume is called.
+func (q *queue) pause() {
+	q.mux.Lock()
+	q.paused = true
+	q.mux.Unlock()
+}
+
+// resume calls the given function, if any, while holding the lock and lets the work items held back by pause in.
+func (q *queue) resume(apply func()) {
+	q.mux.Lock()
+	if apply != nil {
+		apply()
+	}
+	q.paused = false
+	q.broadcast()
+	q.mux.Unlock()
+}
+
+// tracked returns every work item that has not finished, whether it is waiting for room, queued, or running.
+func (q *queue) tracked() (items []*workItem) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	items = make([]*workItem, 0, len(q.live))
+	for _, item := range q.live {
+		items = append(items, item)
+	}
+	return items
+}
+
+// abortInFlight makes begin tell the caller to cancel every work item started from now on and returns the work items
+// that are running now, so they can be canceled as well.
+func (q *queue) abortInFlight() (items []*workItem) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.aborting = true
+	items = make([]*workItem, 0, len(q.running))
+	for item := range q.running {
+		items = append(items, item)
+	}
+	return items
+}
+
+// load returns the number of running and queued work items, the number of workers plus the size of the queue, and if
+// a work item that is not re-entrant would find the queue full.
+func (q *queue) load() (active, capacity int, full bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	length := uint(len(q.items)) + q.reserved
+	return len(q.running) + int(length), int(q.workers + q.size), length >= q.idle+q.size+q.overflow
+}
+
+// kill calls the given function, which must cancel the Pool's context and return the reason it died, then closes the
+// death channel, resolves the waiting FlushTokens with that reason, and removes all work items from the queue. It is
+// all done while holding the lock, so a work item that finishes because the context was canceled can't resolve a
+// FlushToken as if nothing went wrong. The removed work items are returned. If the death channel was already closed,
+// nothing is returned and killed is false.
+func (q *queue) kill(cancel func() error) (items []*workItem, killed bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	cause := cancel()
+	if dead(q.death) {
+		return nil, false
+	}
+	close(q.death)
+	q.resolve(cause)
+	items, q.items = q.items, nil
+	q.broadcast()
+	return items, true
+}
+
+// next returns the index of the first work item the worker with the given number may take or -1 if there is none.
+// With deterministic dispatch, only the assigned worker may take a work item. Work items in a category that is at its
+// limit are skipped until a work item in that category ends. Work items in a serial lane are skipped while a work item
+// in the lane is running or one that was given to the Pool before them is queued. When keeping order, only the work
+// item that was given to the Pool first of those not yet taken may be taken. The lock must be held.
+func (q *queue) next(worker uint) int {
+	heads := q.heads()
+	for i, item := range q.items {
+		if item.assigned != 0 && item.assigned != worker {
+			continue
+		}
+		if q.ordered && !q.first(item) {
+			continue
+		}
+		if limit, ok := q.limits[item.category]; ok && q.categories[item.category] >= limit {
+			continue
+		}
+		if item.lane != "" && (q.lanes[item.lane].running || heads[item.lane] != item.seq) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// heads returns the sequence number of the queued work item of each serial lane that was given to the Pool first. The
+// lock must be held.
+func (q *queue) heads() map[string]uint64 {
+	if len(q.lanes) == 0 {
+		return nil
+	}
+	heads := make(map[string]uint64, len(q.lanes))
+	for _, item := range q.items {
+		if item.lane == "" {
+			continue
+		}
+		if head, ok := heads[item.lane]; !ok || item.seq < head {
+			heads[item.lane] = item.seq
+		}
+	}
+	return heads
+}
+
+// pop blocks until a work item is available to the worker with the given number, death, or the retire channel is
+// closed. The second return value is false on death or retirement. end must be called when the worker is done with the
+// returned work item.
+func (q *queue) pop(worker uint, retire <-chan struct{}) (*workItem, bool) {
+	q.mux.Lock()
+	q.idle++
+	q.broadcast()
+
+	// Wait for a work item the worker may take to show up, death, or retirement.
+	i := q.next(worker)
+	for i < 0 || dead(q.death) || dead(retire) {
+		if dead(q.death) || dead(retire) {
+			q.idle--
+			q.mux.Unlock()
+			return nil, false
+		}
+		changed := q.changed
+		q.mux.Unlock()
+		<-changed
+		q.mux.Lock()
+		i = q.next(worker)
+	}
+
+	// Take the first work item the worker may take.
+	item := q.remove(i)
+	item.worker = worker
+	q.started(item.seq)
+	if item.category != "" {
+		q.categories[item.category]++
+	}
+	if item.lane != "" {
+		q.lanes[item.lane].running = true
+	}
+	q.idle--
+	q.broadcast()
+	q.mux.Unlock()
+
+	return item, true
+}
+
+// push adds the work item to the queue if there is an idle worker to take it or room in the queue or overflow buffer.
+// Re-entrant work items are spilled beyond the queue and overflow buffer, up to the spill limit, if any. With the
+// DropOldest policy, the oldest queued work item is evicted and returned to make room. If the queue was full, the
+// returned channel will close when it is worth trying again.
+//
+// In FIFO mode, work items that find the queue full wait in line and room is only given to the first work item in
+// line, so work items are queued in the order they arrived.
+func (q *queue) push(item *workItem, reentrant bool) (result pushResult, evicted *workItem, changed <-chan struct{}) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	// Figure out where the work item fits, if anywhere. Reserved room counts as taken, unless it is the work item's. A
+	// work item that will not be queued gives its reserved room back right away.
+	length := uint(len(q.items)) + q.reserved
+	if item.reserved && (dead(q.death) || q.closed || item.purged) {
+		q.reserved--
+		item.reserved = false
+		q.broadcast()
+	}
+	switch {
+	case dead(q.death), q.closed:
+		return pushDead, nil, nil
+	case item.purged:
+		return pushPurged, nil, nil
+	case item.reserved:
+		q.reserved--
+		item.reserved = false
+		result = pushAdded
+	case q.paused && !reentrant:
+		q.block(item)
+		return pushFull, nil, q.changed
+	case q.ordered && len(q.items) > 0 && len(q.unstarted) > 0 && q.unstarted[0] == item.seq:
+		result = pushAdded
+	case q.fifo && !reentrant && len(q.line) > 0 && q.line[0] != item:
+		return q.wait(item)
+	case !reentrant && !item.weighed && q.maxBytes > 0 && q.bytes > 0 && q.bytes+item.size > q.maxBytes:
+		return q.wait(item)
+	case length < q.idle+q.size:
+		result = pushAdded
+	case length < q.idle+q.size+q.overflow:
+		result = pushOverflowed
+	default:
+
+		// The queue and overflow buffer are full. Count it once for the work item, whatever happens to it.
+		if !item.full {
+			item.full = true
+			q.filled++
+		}
+		switch {
+		case reentrant && (q.spill == 0 || length < q.idle+q.size+q.overflow+q.spill):
+			result = pushSpilled
+		case q.rejection == DropOldest && len(q.items) > 0:
+			result = pushEvicted
+			evicted = q.remove(q.oldest())
+		case reentrant && q.deadlocked(item):
+			result = pushForced
+		default:
+			return q.wait(item)
+		}
+	}
+
+	// Count the size of the work item until it finishes.
+	if !item.weighed {
+		q.bytes += item.size
+		item.weighed = true
+	}
+
+	// The work item is no longer waiting in line or blocked.
+	q.unblock(item)
+	if len(q.line) > 0 && q.line[0] == item {
+		q.line[0] = nil
+		q.line = q.line[1:]
+	} else if q.ordered {
+		for i, waiting := range q.line {
+			if waiting == item {
+				q.line = append(q.line[:i], q.line[i+1:]...)
+				break
+			}
+		}
+	}
+
+	// With deterministic dispatch, pick the worker that will take the work item.
+	if q.dispatch != nil && q.workers > 0 {
+		item.assigned = uint(q.dispatch.intn(int(q.workers))) + 1
+	}
+
+	// Queue the work item behind the ones with the same or higher priority, or in LIFO order, in front of the ones with
+	// the same priority.
+	i := len(q.items)
+	for i > 0 && (q.items[i-1].priority < item.priority || q.lifo && q.items[i-1].priority == item.priority) {
+		i--
+	}
+	q.items = slices.Insert(q.items, i, item)
+	q.peak = max(q.peak, len(q.items))
+	q.broadcast()
+
+	return result, evicted, nil
+}
+
+// reserve blocks until there is room for a work item in the queue and sets it aside, or until the context ends or the
+// queue dies or closes. The room is given back by release or taken by pushing a work item marked as reserved. It
+// returns pushDead if the queue died or closed and pushFull if the context ended first.
+func (q *queue) reserve(ctx context.Context) pushResult {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	for {
+		switch {
+		case dead(q.death), q.closed:
+			return pushDead
+		case !q.paused && uint(len(q.items))+q.reserved < q.idle+q.size+q.overflow:
+			q.reserved++
+			return pushAdded
+		}
+		changed := q.changed
+		q.mux.Unlock()
+		select {
+		case <-ctx.Done():
+			q.mux.Lock()
+			return pushFull
+		case <-changed:
+		}
+		q.mux.Lock()
+	}
+}
+
+// release gives back room set aside by reserve.
+func (q *queue) release() {
+	q.mux.Lock()
+	q.reserved--
+	q.broadcast()
+	q.mux.Unlock()
+}
+
+// oldest returns the index of the queued work item that was given to the Pool first among those with the lowest
+// priority, so DropOldest never makes room by dropping a work item with a higher priority. The queue must not be empty
+// and the lock must be held.
+func (q *queue) oldest() (oldest int) {
+	for i, item := range q.items {
+		first := q.items[oldest]
+		if item.priority < first.priority || item.priority == first.priority && item.seq < first.seq {
+			oldest = i
+		}
+	}
+	return oldest
+}
+
+// remove removes the queued work item at the index and returns it. The lock must be held.
+func (q *queue) remove(i int) *workItem {
+	item := q.items[i]
+	copy(q.items[i:], q.items[i+1:])
+	q.items[len(q.items)-1] = nil
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
+
+// retire closes the retire channel of a worker and wakes it up if it is waiting for a work item.
+func (q *queue) retire(retire chan struct{}) {
+	q.mux.Lock()
+	close(retire)
+	q.broadcast()
+	q.mux.Unlock()
+}
+
+// track gives the work item the next sequence number and keeps track of it until it has finished, so it can be found
+// by its sequence number.
+func (q *queue) track(item *workItem) {
+	q.mux.Lock()
+	q.seq++
+	item.seq = q.seq
+	q.live[item.seq] = item
+	if q.awaits(item) {
+		q.unstarted = append(q.unstarted, item.seq)
+	}
+	if item.lane != "" {
+		if q.lanes[item.lane] == nil {
+			q.lanes[item.lane] = &lane{}
+		}
+		q.lanes[item.lane].depth++
+	}
+	q.mux.Unlock()
+}
+
+// keepsOrder determines if workers must start work items in the order they were given to the Pool. They must with
+// WithFIFOGuarantee, or with 1 worker and a queue, unless WithDispatchOrder asks for LIFO.
+func keepsOrder(cfg config, workers uint) bool {
+	return cfg.fifoGuarantee || workers == 1 && cfg.queueSize > 0 && cfg.dispatchOrder != LIFO
+}
+
+// awaits determines if the work item must wait for the work items given to the Pool before it to be taken. Without
+// WithFIFOGuarantee, work items with a priority are let past the order, so they are still taken first. The lock must
+// be held.
+func (q *queue) awaits(item *workItem) bool {
+	return q.ordered && (!q.prioritized || item.priority == 0)
+}
+
+// reorder starts or stops keeping work items in the order they were given to the Pool. Work items that are already
+// tracked, but not yet taken, are put in order when it starts. The lock must be held.
+func (q *queue) reorder(ordered bool) {
+	if ordered == q.ordered {
+		return
+	}
+	q.ordered = ordered
+	q.unstarted = nil
+	for seq, item := range q.live {
+		if q.awaits(item) && item.started.IsZero() {
+			q.unstarted = append(q.unstarted, seq)
+		}
+	}
+	slices.Sort(q.unstarted)
+}
+
+// first determines if the work item may be taken when keeping order, because it was already taken once, it does not
+// wait for the order, or no work item that was given to the Pool before it is waiting to be taken. The lock must be
+// held.
+func (q *queue) first(item *workItem) bool {
+	if len(q.unstarted) == 0 || q.unstarted[0] == item.seq {
+		return true
+	}
+	_, found := slices.BinarySearch(q.unstarted, item.seq)
+	return !found
+}
+
+// started stops waiting for the work item with the given sequence number to be taken when keeping order. It returns
+// true if it was waited for. The lock must be held.
+func (q *queue) started(seq uint64) bool {
+	i, found := slices.BinarySearch(q.unstarted, seq)
+	if found {
+		q.unstarted = slices.Delete(q.unstarted, i, i+1)
+	}
+	return found
+}
+
+// wait puts the work item in line, if in FIFO mode, and tells the caller to try again once the queue changes. With the
+// Reject policy, it tells the caller to reject the work item instead. The lock must be held.
+func (q *queue) wait(item *workItem) (result pushResult, evicted *workItem, changed <-chan struct{}) {
+	if q.rejection == Reject {
+		return pushRejected, nil, nil
+	}
+	q.block(item)
+	if q.fifo {
+		waiting := false
+		for _, other := range q.line {
+			waiting = waiting || other == item
+		}
+		if !waiting {
+			q.line = append(q.line, item)
+		}
+	}
+	return pushFull, nil, q.changed
+}
+
+// reentrant determines if the given context belongs to a Work function run by the Pool that owns the queue.
+func (q *queue) reentrant(ctx context.Context) bool {
+	owner, ok := ctx.Value(workerKey{}).(*workItem)
+	return ok && owner.pool.queue == q
+}
+
+// block marks the work item as waiting to be queued. With deadlock detection, the work item that added it is counted
+// as stalled if it is re-entrant, and everything waiting on the queue is woken up to check for a deadlock. The lock
+// must be held.
+func (q *queue) block(item *workItem) {
+	if _, ok := q.blocked[item]; ok {
+		return
+	}
+	q.blocked[item] = struct{}{}
+	if q.detect && item.submitter != nil {
+		q.stalled[item.submitter]++
+		q.broadcast()
+	}
+}
+
+// unblock undoes block once the work item is no longer waiting to be queued. The lock must be held.
+func (q *queue) unblock(item *workItem) {
+	if _, ok := q.blocked[item]; ok {
+		delete(q.blocked, item)
+		if q.detect && item.submitter != nil {
+			if q.stalled[item.submitter]--; q.stalled[item.submitter] == 0 {
+				delete(q.stalled, item.submitter)
+			}
+		}
+	}
+	item.submitter = nil
+}
+
+// deadlocked determines if the re-entrant work item would deadlock the Pool by waiting, because the work items of every
+// worker, including the one that added it, are already waiting to add work items. The lock must be held.
+func (q *queue) deadlocked(item *workItem) bool {
+	if !q.detect || item.submitter == nil {
+		return false
+	}
+	stalled := uint(len(q.stalled))
+	if _, ok := q.stalled[item.submitter]; !ok {
+		stalled++
+	}
+	return stalled >= q.workers
+}
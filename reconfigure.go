@@ -0,0 +1,119 @@
+package ctxerrpool
+
+import (
+	"context"
+	"maps"
+)
+
+// ReconfigureAndDrain changes the configuration of the Pool without replacing it, so every reference to it stays
+// valid. Work items given to the Pool from then on are held back, as are those still waiting for room, except those
+// added from within a Work function. Once all the work that was already queued or running has finished and every Work
+// function has returned, the options are applied and the held back work items are let in. Work that was accepted
+// before the call runs under the old configuration and work held back runs under the new one.
+//
+// Only the options that shape how work items are queued and run may be changed: WithCategoryLimit,
+// WithCircuitBreaker, WithDeterministicDispatch, WithDispatchOrder, WithFIFO, WithMaxRequeues, WithOnBreakerChange,
+// WithOverflow, WithPanicFunc, WithPanicPolicy, WithQueueSize, WithRejectionPolicy, and WithSpillLimit. The circuit
+// breaker starts over closed. Other options have no effect, since producers, workers, and background goroutines depend
+// on them at all times. WithCategoryLimit adds to the existing limits.
+//
+// If the context expires before the Pool is drained, the old configuration is kept, the held back work items are let
+// in, and the context's error is returned. If the Pool dies, the reason it died is returned. ReconfigureAndDrain does
+// not run at the same time as RecycleWorkers. Calling it from within a Work function of the same Pool deadlocks until
+// the context expires.
+func (g *Pool) ReconfigureAndDrain(ctx context.Context, options ...Option) error {
+	g.recycle.Lock()
+	defer g.recycle.Unlock()
+	if g.Dead() {
+		return g.killCause()
+	}
+
+	// Hold new work items back and wait for the accepted work to finish.
+	g.queue.pause()
+	if err := g.drain(ctx); err != nil {
+		g.queue.resume(nil)
+		return err
+	}
+
+	// Apply the options to a copy of the configuration.
+	cfg := g.cfg
+	cfg.categoryLimits = maps.Clone(cfg.categoryLimits)
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	g.workerMux.Lock()
+	workers := append([]*worker(nil), g.workers...)
+	g.workerMux.Unlock()
+
+	// Change what the workers and the queue use and let the held back work items in. Nothing is running, and workers
+	// only read the configuration after taking a work item from the queue and before finishing it there, so writing it
+	// under the queue's lock is enough to hand it to them.
+	g.queue.resume(func() {
+		g.breaker = newBreaker(cfg)
+		g.cfg.breakerCooldown = cfg.breakerCooldown
+		g.cfg.breakerThreshold = cfg.breakerThreshold
+		g.cfg.breakerWindow = cfg.breakerWindow
+		g.cfg.maxRequeues = cfg.maxRequeues
+		g.cfg.onBreakerChange = cfg.onBreakerChange
+		g.cfg.panicFunc = cfg.panicFunc
+		g.cfg.panicPolicy = cfg.panicPolicy
+		g.cfg.categoryLimits = cfg.categoryLimits
+		g.cfg.deterministic = cfg.deterministic
+		g.cfg.dispatchOrder = cfg.dispatchOrder
+		g.cfg.fifo = cfg.fifo
+		g.cfg.overflow = cfg.overflow
+		g.cfg.queueSize = cfg.queueSize
+		g.cfg.rejection = cfg.rejection
+		g.cfg.seed = cfg.seed
+		g.cfg.spillLimit = cfg.spillLimit
+		q := g.queue
+		q.dispatch = newDispatcher(cfg, cfg.seed)
+		q.fifo = cfg.fifo
+		q.lifo = cfg.dispatchOrder == LIFO
+		q.limits = cfg.categoryLimits
+		q.overflow = cfg.overflow
+		q.rejection = cfg.rejection
+		q.size = cfg.queueSize
+		q.spill = cfg.spillLimit
+		q.reorder(keepsOrder(cfg, q.workers))
+		for _, w := range workers {
+			w.dispatch = newDispatcher(cfg, cfg.seed+int64(w.id))
+		}
+	})
+
+	return nil
+}
+
+// drain waits until every work item the Pool accepted has finished, apart from the ones held back by the queue's
+// pause, and every Work function has returned.
+func (g *Pool) drain(ctx context.Context) error {
+	for {
+		drained, changed := g.queue.drained()
+		if drained {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.death:
+			return g.killCause()
+		case <-changed:
+		}
+	}
+
+	// Wait for Work functions that outlived their work items.
+	done := make(chan struct{})
+	g.spawn(func() {
+		g.working.Wait()
+		close(done)
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-g.death:
+		return g.killCause()
+	case <-done:
+		return nil
+	}
+}
@@ -0,0 +1,153 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestReconfigureAndDrain confirms that work items given to the Pool while it drains are held back until the accepted
+// work has finished and then run under the new configuration.
+func TestReconfigureAndDrain(t *testing.T) {
+
+	// Create a wait pool that waits for the rejection to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker and no queue.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have the ctxerrpool.ErrQueueFull error from the new configuration.
+		if !errors.Is(err, ctxerrpool.ErrQueueFull) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{}, 4)
+	work := func(workCtx context.Context, data interface{}) error {
+		started <- struct{}{}
+		<-gate
+		return nil
+	}
+	pool.AddWorkItem(ctx, work, "old")
+	<-started
+
+	// Reconfigure the pool to have a queue of 1 that rejects work items when it is full.
+	reconfigured := make(chan error)
+	go func() {
+		reconfigured <- pool.ReconfigureAndDrain(ctx, ctxerrpool.WithQueueSize(1),
+			ctxerrpool.WithRejectionPolicy(ctxerrpool.Reject))
+	}()
+
+	// Confirm a work item given to the pool while it drains is held back.
+	held := make(chan struct{})
+	go func() {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			close(held)
+			return nil
+		}, "held")
+	}()
+	select {
+	case <-held:
+		t.Errorf("A work item ran before the pool was drained.")
+		t.FailNow()
+	case err := <-reconfigured:
+		t.Errorf("The pool was reconfigured before it was drained. Error: %v", err)
+		t.FailNow()
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Let the accepted work finish and confirm the held back work item runs.
+	close(gate)
+	if err := <-reconfigured; err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	<-held
+	pool.Wait()
+
+	// Confirm the new configuration is used. Occupy the worker, fill the queue, and then be rejected.
+	gate = make(chan struct{})
+	pool.AddWorkItem(ctx, work, "busy")
+	<-started
+	pool.AddWorkItem(ctx, work, "queued")
+	pool.AddWorkItem(ctx, work, "rejected")
+	if rejected := pool.Stats().Rejected; rejected != 1 {
+		t.Errorf("Expected 1 rejected work item, got %d.", rejected)
+		t.FailNow()
+	}
+	close(gate)
+	pool.Wait()
+	wg.Wait()
+}
+
+// TestReconfigureAndDrainTimeout confirms that the old configuration is kept and held back work items are let in when
+// the context expires before the Pool is drained.
+func TestReconfigureAndDrainTimeout(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep a worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, nil)
+	<-started
+
+	// Give up on reconfiguring before the work is done.
+	reconfigured := make(chan error)
+	reconfigureCtx, reconfigureCancel := context.WithCancel(ctx)
+	go func() {
+		reconfigured <- pool.ReconfigureAndDrain(reconfigureCtx, ctxerrpool.WithQueueSize(1))
+	}()
+	noop := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	waitFor(t, func() bool {
+		return errors.Is(pool.AddWorkItemContext(ctx, noop, nil), ctxerrpool.ErrQueueFull)
+	})
+	held := make(chan struct{})
+	go func() {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			close(held)
+			return nil
+		}, nil)
+	}()
+	waitFor(t, func() bool {
+		return pool.Blocked() == 1
+	})
+	reconfigureCancel()
+
+	// Confirm the error and that the held back work item runs on the other worker.
+	if err := <-reconfigured; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v.", err)
+		t.FailNow()
+	}
+	<-held
+	close(gate)
+	pool.Wait()
+}
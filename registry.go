@@ -0,0 +1,91 @@
+package ctxerrpool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrDuplicateName indicates that a Pool is already registered under the given name.
+var ErrDuplicateName = errors.New("a pool is already registered under the name")
+
+// registry holds the Pools registered by name. Pools are only registered when Register is called.
+var registry = struct {
+	mux   sync.Mutex
+	pools map[string]*Pool
+}{
+	pools: make(map[string]*Pool),
+}
+
+// Register makes the Pool available under the given name to Lookup, Range, and KillAll until it dies. ErrDuplicateName
+// is returned if another Pool is already registered under the name. Registering a dead Pool has no effect. The Pool
+// keeps the name it was last registered under for String.
+func Register(name string, pool *Pool) error {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+	if _, ok := registry.pools[name]; ok {
+		return ErrDuplicateName
+	}
+	if pool.Dead() {
+		return nil
+	}
+	registry.pools[name] = pool
+	pool.name.Store(&name)
+
+	// Remove the Pool from the registry when it is killed.
+	context.AfterFunc(pool.ctx, func() {
+		Deregister(name, pool)
+	})
+
+	return nil
+}
+
+// Deregister removes the Pool from the registry if it is registered under the given name.
+func Deregister(name string, pool *Pool) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+	if registry.pools[name] == pool {
+		delete(registry.pools, name)
+	}
+}
+
+// KillAll kills every registered Pool. It is meant for shutting down a program.
+func KillAll() {
+	Range(func(name string, pool *Pool) bool {
+		pool.Kill()
+		return true
+	})
+}
+
+// Lookup returns the Pool registered under the given name. ok is false if there is none.
+func Lookup(name string) (pool *Pool, ok bool) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+	pool, ok = registry.pools[name]
+	return pool, ok
+}
+
+// Range calls the function for every registered Pool in order of name until it returns false. The registry is not
+// locked while the function runs, so it may register and kill Pools.
+func Range(fn func(name string, pool *Pool) bool) {
+
+	// Take a snapshot of the registry.
+	registry.mux.Lock()
+	names := make([]string, 0, len(registry.pools))
+	for name := range registry.pools {
+		names = append(names, name)
+	}
+	pools := make(map[string]*Pool, len(registry.pools))
+	for name, pool := range registry.pools {
+		pools[name] = pool
+	}
+	registry.mux.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		if !fn(name, pools[name]) {
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package ctxerrpool_test
+
+import (
+	"errors"
+	"testing"
+
+	"ctxerrpool"
+)
+
+// TestRegister confirms that pools can be registered, looked up, and ranged over, and that names are unique.
+func TestRegister(t *testing.T) {
+
+	// Create and register two worker pools.
+	first := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	defer first.Kill()
+	second := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	defer second.Kill()
+	if err := ctxerrpool.Register("test-first", first); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	defer ctxerrpool.Deregister("test-first", first)
+	if err := ctxerrpool.Register("test-second", second); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	defer ctxerrpool.Deregister("test-second", second)
+
+	// Confirm a name can not be used twice.
+	if err := ctxerrpool.Register("test-first", second); !errors.Is(err, ctxerrpool.ErrDuplicateName) {
+		t.Errorf("Expected a duplicate name error. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the pools can be found.
+	if pool, ok := ctxerrpool.Lookup("test-first"); !ok || pool != first {
+		t.Error("The first pool was not found.")
+		t.FailNow()
+	}
+	found := make(map[string]*ctxerrpool.Pool)
+	ctxerrpool.Range(func(name string, pool *ctxerrpool.Pool) bool {
+		found[name] = pool
+		return true
+	})
+	if found["test-first"] != first || found["test-second"] != second {
+		t.Errorf("Unexpected pools: %v.", found)
+		t.FailNow()
+	}
+}
+
+// TestRegisterDeath confirms that a pool is removed from the registry when it dies.
+func TestRegisterDeath(t *testing.T) {
+
+	// Create and register a worker pool.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	if err := ctxerrpool.Register("test-death", pool); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Kill the pool and confirm it is removed.
+	pool.Kill()
+	waitFor(t, func() bool {
+		_, ok := ctxerrpool.Lookup("test-death")
+		return !ok
+	})
+
+	// Confirm the name can be used again.
+	other := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	defer other.Kill()
+	if err := ctxerrpool.Register("test-death", other); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	defer ctxerrpool.Deregister("test-death", other)
+}
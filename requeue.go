@@ -0,0 +1,103 @@
+package ctxerrpool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxRequeues is how many times a work item may be requeued with Requeue unless WithMaxRequeues says otherwise.
+const DefaultMaxRequeues = 10
+
+// ErrRequeueLimit indicates that the work item asked to be requeued more times than allowed. The reported error wraps
+// the reason given to the last Requeue.
+var ErrRequeueLimit = errors.New("work item was requeued too many times")
+
+// requeueError is returned by a Work function to ask for its work item to be requeued.
+type requeueError struct {
+	after  time.Duration
+	reason error
+}
+
+// Error implements the error interface.
+func (e *requeueError) Error() string {
+	return fmt.Sprintf("work item asked to be requeued after %v: %v", e.after, e.reason)
+}
+
+// Unwrap returns the reason the work item asked to be requeued.
+func (e *requeueError) Unwrap() error {
+	return e.reason
+}
+
+// Requeue returns an error that asks the Pool to run the work item again after the given delay instead of finishing
+// it. Return it from a Work function, possibly wrapped, to yield or retry cooperatively. A requeued work item is not
+// failed and is not reported to the error handler. It keeps its context, so it still ends when the context does, and
+// still counts for Wait. Once the delay has passed, it is queued like a work item added from within a Work function,
+// so it does not block a worker. A work item may be requeued up to the limit set with WithMaxRequeues, after which
+// it fails with ErrRequeueLimit wrapping the reason.
+func Requeue(after time.Duration, reason error) error {
+	return &requeueError{
+		after:  after,
+		reason: reason,
+	}
+}
+
+// requeued determines if the error asks for the work item to be requeued. If it does and the work item may be
+// requeued again, the request is recorded and the returned error is nil. If the work item has been requeued too many
+// times, an error wrapping ErrRequeueLimit is returned.
+func (item *workItem) requeued(err error) error {
+	var requeue *requeueError
+	if !errors.As(err, &requeue) {
+		return err
+	}
+	item.mux.Lock()
+	defer item.mux.Unlock()
+	if item.requeues >= item.pool.cfg.maxRequeues {
+		if requeue.reason == nil {
+			return ErrRequeueLimit
+		}
+		return fmt.Errorf("%w: %w", ErrRequeueLimit, requeue.reason)
+	}
+	if !item.pool.budget.allow() {
+		if requeue.reason == nil {
+			return ErrRetryBudget
+		}
+		return fmt.Errorf("%w: %w", ErrRetryBudget, requeue.reason)
+	}
+	item.requeue = requeue
+	return nil
+}
+
+// requeue queues the work item again after the delay it asked for, if it asked to be requeued and has not failed. It
+// returns false if the work item should be finished instead.
+func (g *Pool) requeue(item *workItem) bool {
+	item.mux.Lock()
+	requeue := item.requeue
+	item.requeue = nil
+	if requeue == nil || item.err != nil {
+		item.mux.Unlock()
+		return false
+	}
+	item.requeues++
+	item.mux.Unlock()
+	item.handle.enter(StateQueued, g.cfg.clock.Now())
+
+	// Wait for the delay without holding up the worker. If the context ends first, sendWorkItem reports it.
+	g.spawn(func() {
+		if requeue.after > 0 {
+			timer := g.cfg.clock.NewTimer(requeue.after)
+			select {
+			case <-timer.C():
+			case <-item.ctx.Done():
+				timer.Stop()
+			case <-g.death:
+				timer.Stop()
+				g.drop(item)
+				return
+			}
+		}
+		g.sendWorkItem(item.ctx, item, true)
+	})
+
+	return true
+}
@@ -0,0 +1,95 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestRequeue confirms that a work item that asks to be requeued runs again without being reported or failed.
+func TestRequeue(t *testing.T) {
+
+	// Create an error for the reason to requeue.
+	errBusy := errors.New("busy")
+
+	// Keep track of the error the work item finished with.
+	var finishErr error
+	finishes := 0
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	}, ctxerrpool.WithOnFinish(func(info ctxerrpool.FinishInfo) {
+		finishErr = info.Err
+		finishes++
+	}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a work item that asks to be requeued twice before it succeeds.
+	var runs int64
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		if atomic.AddInt64(&runs, 1) < 3 {
+			return ctxerrpool.Requeue(time.Millisecond, errBusy)
+		}
+		return nil
+	}, nil)
+	pool.Wait()
+
+	// Confirm the work item ran 3 times and finished once, successfully.
+	if runs := atomic.LoadInt64(&runs); runs != 3 || finishes != 1 || finishErr != nil {
+		t.Errorf("Expected 3 runs and 1 successful finish, got %d runs and %d finishes with error %v.", runs, finishes,
+			finishErr)
+		t.FailNow()
+	}
+}
+
+// TestRequeueLimit confirms that a work item that keeps asking to be requeued fails with ErrRequeueLimit.
+func TestRequeueLimit(t *testing.T) {
+
+	// Create an error for the reason to requeue.
+	errBusy := errors.New("busy")
+
+	// Create a wait pool that waits for the error to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 1 worker that allows 2 requeues.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should have the ctxerrpool.ErrRequeueLimit error wrapping the reason.
+		if !errors.Is(err, ctxerrpool.ErrRequeueLimit) || !errors.Is(err, errBusy) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	}, ctxerrpool.WithMaxRequeues(2))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool a work item that always asks to be requeued.
+	var runs int64
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		atomic.AddInt64(&runs, 1)
+		return ctxerrpool.Requeue(0, errBusy)
+	}, nil)
+	pool.Wait()
+	wg.Wait()
+
+	// Confirm the work item ran once and was requeued twice.
+	if runs := atomic.LoadInt64(&runs); runs != 3 {
+		t.Errorf("Expected 3 runs, got %d.", runs)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,103 @@
+package ctxerrpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrReservationDone indicates that a Reservation was already committed, aborted, or expired.
+var ErrReservationDone = errors.New("the reservation was already committed, aborted, or expired")
+
+// Reservation is room in a Pool's queue set aside by Reserve for a work item that is not ready yet. It must be
+// committed or aborted, or it expires with the context it was made with.
+type Reservation struct {
+	ctx  context.Context
+	done bool
+	mux  sync.Mutex
+	pool *Pool
+	stop func() bool
+}
+
+// Reserve blocks until there is room in the queue for a work item, as AddWorkItemContext would find it, and sets it
+// aside, so the work item can be prepared without the risk of finding the Pool busy afterward. Reserved room counts as
+// taken for every other work item. The room is taken by Commit or given back by Abort. If neither is called before the
+// context ends, the room is given back automatically, so a forgotten Reservation can't wedge the Pool.
+//
+// If the context ends first, an error matching ErrCantDo is returned. If the Pool is dead, the reason it died is
+// returned. If the context is nil, the Reservation never expires and Commit treats the work item like Submit does.
+func (g *Pool) Reserve(ctx context.Context) (*Reservation, error) {
+	waitCtx := ctx
+	if waitCtx == nil {
+		waitCtx = context.Background()
+	}
+
+	// Wait for room.
+	start := g.cfg.clock.Now()
+	switch g.queue.reserve(waitCtx) {
+	case pushDead:
+		return nil, g.killCause()
+	case pushFull:
+		return nil, g.cantDo(waitCtx, g.cfg.clock.Now().Sub(start))
+	}
+
+	// Give the room back if the context ends first.
+	r := &Reservation{
+		ctx:  ctx,
+		pool: g,
+		stop: func() bool { return false },
+	}
+	if ctx != nil {
+		r.stop = context.AfterFunc(ctx, r.Abort)
+	}
+
+	return r, nil
+}
+
+// Abort gives the reserved room back. It does nothing if the Reservation was already committed, aborted, or expired.
+func (r *Reservation) Abort() {
+	if !r.finish() {
+		return
+	}
+	r.stop()
+	r.pool.queue.release()
+}
+
+// Commit gives the Work function to the Pool in the reserved room, with the options applied like Submit, using the
+// context the Reservation was made with. It never blocks. ErrReservationDone is returned if the Reservation was already
+// committed, aborted, or expired. Otherwise, the errors are those of Submit. The Reservation is kept if the Work
+// function is nil or the options are invalid.
+func (r *Reservation) Commit(work Work, opts ...SubmitOption) (WorkID, error) {
+	if work == nil {
+		return 0, ErrNilWork
+	}
+	s, err := newSubmission(opts)
+	if err != nil {
+		return 0, err
+	}
+	if !r.finish() {
+		return 0, ErrReservationDone
+	}
+	r.stop()
+
+	// Give the work item the reserved room.
+	g := r.pool
+	ctx, item := g.prepare(r.ctx, work, s)
+	item.reserved = true
+	if id := g.addWorkItem(ctx, item); id != 0 {
+		return id, nil
+	}
+
+	return 0, g.killCause()
+}
+
+// finish marks the Reservation as done. It returns false if it already was.
+func (r *Reservation) finish() bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.done {
+		return false
+	}
+	r.done = true
+	return true
+}
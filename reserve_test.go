@@ -0,0 +1,235 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestReserve confirms that reserved room is taken from every other work item until it is committed.
+func TestReserve(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 1 work item in the queue, and make the worker busy.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+	<-pool.Started()
+	release := busy(pool)
+
+	// Reserve room, which leaves none for anyone else.
+	reservation, err := pool.Reserve(context.Background())
+	if err != nil {
+		t.Errorf("Failed to reserve room: %v", err)
+		t.FailNow()
+	}
+	if !pool.Full() {
+		t.Error("The reserved room was not counted as taken.")
+		t.FailNow()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err = pool.Reserve(ctx); !errors.Is(err, ctxerrpool.ErrCantDo) {
+		t.Errorf("Expected a second reservation to find no room, got: %v", err)
+		t.FailNow()
+	}
+	err = pool.AddWorkItemContext(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	if !errors.Is(err, ctxerrpool.ErrQueueFull) {
+		t.Errorf("Expected the work item to find no room, got: %v", err)
+		t.FailNow()
+	}
+
+	// Commit a work item, which takes the reserved room, and confirm the reservation can't be used again.
+	done := make(chan struct{})
+	if _, err = reservation.Commit(nil); !errors.Is(err, ctxerrpool.ErrNilWork) {
+		t.Errorf("Expected a nil Work function to be refused, got: %v", err)
+		t.FailNow()
+	}
+	id, err := reservation.Commit(func(workCtx context.Context, data interface{}) error {
+		close(done)
+		return nil
+	})
+	if err != nil || id == 0 {
+		t.Errorf("Failed to commit the reservation: %v", err)
+		t.FailNow()
+	}
+	if _, err = reservation.Commit(func(workCtx context.Context, data interface{}) error {
+		return nil
+	}); !errors.Is(err, ctxerrpool.ErrReservationDone) {
+		t.Errorf("Expected the reservation to be done, got: %v", err)
+		t.FailNow()
+	}
+	reservation.Abort()
+	if stats := pool.Stats(); stats.Queued != 1 {
+		t.Errorf("Expected 1 queued work item, got %d.", stats.Queued)
+		t.FailNow()
+	}
+
+	// Confirm the committed work item runs.
+	release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("The committed work item did not run.")
+		t.FailNow()
+	}
+	pool.Wait()
+
+	// Confirm a dead Pool refuses reservations.
+	pool.Kill()
+	if _, err = pool.Reserve(context.Background()); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected the reason the Pool died, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestReserveAbort confirms that aborted and expired reservations give their room back.
+func TestReserveAbort(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 1 work item in the queue, and make the worker busy.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+	<-pool.Started()
+	release := busy(pool)
+	defer release()
+	loadFactor := pool.LoadFactor()
+
+	// Reserve and abort many times and confirm no room is lost.
+	for i := 0; i < 100; i++ {
+		reservation, err := pool.Reserve(context.Background())
+		if err != nil {
+			t.Errorf("Failed to reserve room on attempt %d: %v", i, err)
+			t.FailNow()
+		}
+		reservation.Abort()
+		reservation.Abort()
+	}
+	if pool.Full() || pool.LoadFactor() != loadFactor {
+		t.Error("Aborted reservations did not give their room back.")
+		t.FailNow()
+	}
+
+	// Let a reservation expire and confirm its room is given back and it can't be committed.
+	ctx, cancel := context.WithCancel(context.Background())
+	reservation, err := pool.Reserve(ctx)
+	if err != nil {
+		t.Errorf("Failed to reserve room: %v", err)
+		t.FailNow()
+	}
+	cancel()
+	waitFor(t, func() bool {
+		return !pool.Full()
+	})
+	if _, err = reservation.Commit(func(workCtx context.Context, data interface{}) error {
+		return nil
+	}); !errors.Is(err, ctxerrpool.ErrReservationDone) {
+		t.Errorf("Expected the reservation to be expired, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestReserveDropOldest confirms that the DropOldest policy never drops reserved room, only queued work items.
+func TestReserveDropOldest(t *testing.T) {
+
+	// Create a worker pool with 1 worker, room for 2 queued work items, and the DropOldest policy, and make the worker
+	// busy.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(2),
+		ctxerrpool.WithRejectionPolicy(ctxerrpool.DropOldest))
+	defer pool.Kill()
+	<-pool.Started()
+	release := busy(pool)
+	defer release()
+
+	// Reserve all the room, then confirm a work item finds none instead of dropping a reservation.
+	first, err := pool.Reserve(context.Background())
+	if err != nil {
+		t.Errorf("Failed to reserve room: %v", err)
+		t.FailNow()
+	}
+	second, err := pool.Reserve(context.Background())
+	if err != nil {
+		t.Errorf("Failed to reserve room: %v", err)
+		t.FailNow()
+	}
+	err = pool.AddWorkItemContext(context.Background(), func(workCtx context.Context, data interface{}) error {
+		return nil
+	}, nil)
+	if !errors.Is(err, ctxerrpool.ErrQueueFull) {
+		t.Errorf("Expected the work item to find no room, got: %v", err)
+		t.FailNow()
+	}
+
+	// Fill one reservation and confirm a new work item drops the queued work item, but not the other reservation.
+	work := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	if _, err = first.Commit(work); err != nil {
+		t.Errorf("Failed to commit the reservation: %v", err)
+		t.FailNow()
+	}
+	pool.AddWorkItem(context.Background(), work, nil)
+	if stats := pool.Stats(); stats.DroppedOldest != 1 || stats.Queued != 1 {
+		t.Errorf("Expected 1 dropped and 1 queued work item, got %d and %d.", stats.DroppedOldest, stats.Queued)
+		t.FailNow()
+	}
+	if _, err = second.Commit(work); err != nil {
+		t.Errorf("Failed to commit the reservation: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestReserveCommitClosed confirms that committing a reservation to a Pool that stopped accepting work items, but did
+// not die yet, gives the room back.
+func TestReserveCommitClosed(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 1 work item in the queue, and make the worker busy.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(1))
+	defer pool.Kill()
+	<-pool.Started()
+	release := busy(pool)
+	loadFactor := pool.LoadFactor()
+
+	// Reserve the room in the queue.
+	reservation, err := pool.Reserve(context.Background())
+	if err != nil {
+		t.Errorf("Failed to reserve room: %v", err)
+		t.FailNow()
+	}
+
+	// Stop accepting work items while the worker is still busy.
+	killed := make(chan struct{})
+	go func() {
+		pool.KillWithPolicy(ctxerrpool.KillPolicy{})
+		close(killed)
+	}()
+	noop := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	waitFor(t, func() bool {
+		return errors.Is(pool.AddWorkItemContext(context.Background(), noop, nil), ctxerrpool.ErrPoolKilled)
+	})
+
+	// Confirm the committed work item is dropped and the room is given back before the pool dies.
+	finished := make(chan error, 1)
+	if _, err = reservation.Commit(noop, ctxerrpool.WithCallback(func(err error) {
+		finished <- err
+	})); err != nil {
+		t.Errorf("Failed to commit: %v", err)
+		t.FailNow()
+	}
+	if err = <-finished; !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected the work item to be dropped, got: %v", err)
+		t.FailNow()
+	}
+	if pool.Dead() || pool.Full() || pool.LoadFactor() != loadFactor {
+		t.Error("The committed reservation did not give its room back.")
+		t.FailNow()
+	}
+
+	// Let the pool die.
+	release()
+	<-killed
+}
@@ -0,0 +1,47 @@
+package ctxerrpool
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result describes a finished work item whose Work function produced a value. It is what the Pool's helpers that
+// collect values deliver, so every completion comes with its data, value, error, and timing in one place.
+type Result[T any] struct {
+
+	// Data is the data that was given with the work item.
+	Data interface{}
+
+	// Duration is how long the Work function ran. It is zero if the work item never started.
+	Duration time.Duration
+
+	// Err is the error that ended the work item, if any.
+	Err error
+
+	// ID identifies the work item.
+	ID WorkID
+
+	// Value is the value the Work function produced. It is the zero value if Err is not nil.
+	Value T
+}
+
+// MarshalJSON implements the json.Marshaler interface. The error is encoded as its message, since most errors can not
+// be encoded as JSON.
+func (r Result[T]) MarshalJSON() ([]byte, error) {
+	encoded := struct {
+		Data     interface{}   `json:"data"`
+		Duration time.Duration `json:"duration"`
+		Err      string        `json:"err,omitempty"`
+		ID       WorkID        `json:"id"`
+		Value    T             `json:"value"`
+	}{
+		Data:     r.Data,
+		Duration: r.Duration,
+		ID:       r.ID,
+		Value:    r.Value,
+	}
+	if r.Err != nil {
+		encoded.Err = r.Err.Error()
+	}
+	return json.Marshal(encoded)
+}
@@ -0,0 +1,35 @@
+package ctxerrpool_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestResultJSON confirms that a Result can be encoded as JSON, including its error.
+func TestResultJSON(t *testing.T) {
+
+	// Encode a Result with an error.
+	result := ctxerrpool.Result[int]{
+		Data:     "data",
+		Duration: time.Second,
+		Err:      errors.New("test"),
+		ID:       3,
+		Value:    5,
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the encoding.
+	expected := `{"data":"data","duration":1000000000,"err":"test","id":3,"value":5}`
+	if string(encoded) != expected {
+		t.Errorf("Unexpected JSON: %s", encoded)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,379 @@
+package ctxerrpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryBudgetTokens is how many tokens the retry budget set with WithRetryBudget holds by default. See
+// WithRetryBudgetTokens.
+const RetryBudgetTokens = 10
+
+// ErrRetryBudget indicates that a work item was not retried because the Pool's retry budget was used up. See
+// WithRetryBudget.
+var ErrRetryBudget = errors.New("the pool's retry budget was used up")
+
+// ErrRetryDeadline indicates that Retry gave up early because waiting before the next attempt would not have left
+// time for it before the context's deadline. It matches context.DeadlineExceeded with errors.Is.
+var ErrRetryDeadline = fmt.Errorf("the backoff before the next attempt would pass the deadline: %w",
+	context.DeadlineExceeded)
+
+// attemptKey is the context key for the attempt number given to a function by Retry.
+type attemptKey struct{}
+
+// budgetKey is the context key for the time budget of an attempt given to a function by Retry.
+type budgetKey struct{}
+
+// DeadlineStrategy determines what Retry does when waiting before the next attempt would not leave time for it before
+// the context's deadline. The time an attempt needs is the policy's AttemptTimeout, or none if it is zero.
+type DeadlineStrategy uint8
+
+const (
+
+	// IgnoreDeadline makes Retry wait as usual, even though the context may end while it waits. This is the default.
+	IgnoreDeadline DeadlineStrategy = iota
+
+	// FinalAttempt makes Retry shorten the wait so the attempt has the time it needs and make it the final attempt.
+	// Without an AttemptTimeout, the final attempt is made right away.
+	FinalAttempt
+
+	// GiveUpEarly makes Retry return the last error joined with ErrRetryDeadline instead of waiting.
+	GiveUpEarly
+)
+
+// Jitter determines how RetryPolicy randomizes the wait between attempts, so many callers that failed at the same time
+// do not all retry at the same time.
+type Jitter uint8
+
+const (
+
+	// NoJitter waits exactly the exponential backoff. This is the default.
+	NoJitter Jitter = iota
+
+	// FullJitter waits a random time between zero and the exponential backoff.
+	FullJitter
+
+	// EqualJitter waits half the exponential backoff plus a random time up to the other half.
+	EqualJitter
+
+	// DecorrelatedJitter waits a random time between Backoff and three times the previous wait, capped by MaxBackoff.
+	// Multiplier is not used.
+	DecorrelatedJitter
+)
+
+// Backoffer decides how long Retry waits between attempts. NextDelay is given the attempt that just failed, starting
+// at 1, and the previous wait, which is zero after the first attempt. It may be called concurrently by Retry calls
+// that share the policy. When the context has a deadline, it is also called ahead of time for the waits still to come,
+// to work out the share of the time left given by RetryBudget.
+type Backoffer interface {
+	NextDelay(attempt int, prev time.Duration) time.Duration
+}
+
+// RetryPolicy describes how Retry calls a function until it succeeds.
+type RetryPolicy struct {
+
+	// Attempts is the most times the function is called. Zero means 1.
+	Attempts uint
+
+	// AttemptTimeout is how long each attempt may take before its context is canceled. Zero means there is no limit.
+	AttemptTimeout time.Duration
+
+	// Backoff is how long to wait before the second attempt.
+	Backoff time.Duration
+
+	// Backoffer decides how long to wait between attempts instead of Backoff, Jitter, MaxBackoff, and Multiplier, if
+	// it is not nil.
+	Backoffer Backoffer
+
+	// Clock is used to wait between attempts. The default uses the time package.
+	Clock Clock
+
+	// Jitter is how the wait between attempts is randomized. The default is NoJitter.
+	Jitter Jitter
+
+	// MaxBackoff is the longest wait between attempts. Zero means there is no limit.
+	MaxBackoff time.Duration
+
+	// Multiplier is what the wait is multiplied by after each attempt. Zero means 2. Use 1 for a constant wait.
+	Multiplier float64
+
+	// OnDeadline is what to do when the wait before the next attempt would not leave time for it before the
+	// context's deadline. The default is IgnoreDeadline.
+	OnDeadline DeadlineStrategy
+
+	// RetryIf decides if a failed attempt is retried based on its error, if it is not nil. Errors matching
+	// context.Canceled or context.DeadlineExceeded are never retried, whatever it says, unless the attempt ran out of
+	// its own AttemptTimeout. Use RetryOn for the common case. By default, every other error is retried.
+	RetryIf func(err error) bool
+
+	// Rand returns a pseudo-random number in [0.0, 1.0) for the jitter. It must be safe for concurrent use if the
+	// policy is shared. Set it in tests so the waits can be asserted. The default is rand.Float64.
+	Rand func() float64
+
+	// throttle is the retry budget of the Pool the policy was given to with WithRetryPolicy, if any.
+	throttle *retryBudget
+}
+
+// NextDelay returns how long Retry waits after the given attempt, starting at 1, when the previous wait was prev. It
+// implements Backoffer, so the waits a policy makes can be checked ahead of time.
+func (p RetryPolicy) NextDelay(attempt int, prev time.Duration) time.Duration {
+	if p.Backoffer != nil {
+		return p.Backoffer.NextDelay(attempt, prev)
+	}
+	random := p.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+
+	// Randomize the exponential backoff.
+	delay := p.delay(uint(attempt))
+	switch p.Jitter {
+	case FullJitter:
+		return time.Duration(random() * float64(delay))
+	case EqualJitter:
+		return delay/2 + time.Duration(random()*float64(delay-delay/2))
+	case DecorrelatedJitter:
+		if prev < p.Backoff {
+			prev = p.Backoff
+		}
+		delay = p.Backoff + time.Duration(random()*float64(3*prev-p.Backoff))
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+
+	return delay
+}
+
+// budget returns the time the given attempt, which starts at 1, may take so the remaining attempts and the waits
+// between them fit before the context's deadline, capped by AttemptTimeout. prev is the wait before the attempt. The
+// waits are those of NextDelay, through the Backoffer if there is one, and the longest the jitter allows otherwise.
+// ok is false if there is no limit.
+func (p RetryPolicy) budget(ctx context.Context, now time.Time, attempt, attempts uint,
+	prev time.Duration) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return p.AttemptTimeout, p.AttemptTimeout > 0
+	}
+
+	// Share the time left after the waits evenly between the remaining attempts.
+	longest := p
+	longest.Rand = func() float64 {
+		return 1
+	}
+	left := deadline.Sub(now)
+	for i := attempt; i < attempts; i++ {
+		prev = longest.NextDelay(int(i), prev)
+		left -= prev
+	}
+	budget := max(left/time.Duration(attempts-attempt+1), 0)
+	if p.AttemptTimeout > 0 {
+		budget = min(budget, p.AttemptTimeout)
+	}
+
+	return budget, true
+}
+
+// retryable determines if a failed attempt with the given error is retried. timedOut is true if the attempt ran out of
+// its own AttemptTimeout.
+func (p RetryPolicy) retryable(err error, timedOut bool) bool {
+	if !timedOut && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return false
+	}
+	return p.RetryIf == nil || p.RetryIf(err)
+}
+
+// delay returns the exponential backoff after the given attempt, which starts at 1, before any jitter.
+func (p RetryPolicy) delay(attempt uint) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(p.Backoff)
+	for i := uint(1); i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(delay)
+}
+
+// Retry calls the function until it succeeds, the policy's attempts are used up, or the context ends. The attempt
+// number, starting at 1, is available to the function through RetryAttempt and its share of the time left through
+// RetryBudget. Retry waits between attempts according to the policy and stops waiting when the context ends. The
+// policy's OnDeadline decides what happens when the wait would not leave time for the next attempt. The error from
+// the last attempt is returned. If the context ended, its error is returned as well.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	clock := policy.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	attempts := policy.Attempts
+	if attempts == 0 {
+		attempts = 1
+	}
+
+	var delay time.Duration
+	var err error
+	for attempt := uint(1); ; attempt++ {
+
+		// Make the attempt.
+		budget, limited := policy.budget(ctx, clock.Now(), attempt, attempts, delay)
+		var timedOut bool
+		timedOut, err = try(ctx, policy, attempt, budget, limited, fn)
+		policy.throttle.record(err != nil)
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return errors.Join(err, ctxErr)
+		}
+		if !policy.retryable(err, timedOut) {
+			return err
+		}
+		if !policy.throttle.allow() {
+			return errors.Join(err, ErrRetryBudget)
+		}
+
+		// Wait before the next attempt, unless it would not leave time for it before the deadline.
+		delay = policy.NextDelay(int(attempt), delay)
+		if deadline, ok := ctx.Deadline(); ok && policy.OnDeadline != IgnoreDeadline {
+			left := deadline.Sub(clock.Now()) - policy.AttemptTimeout
+			if delay >= left {
+				if policy.OnDeadline == GiveUpEarly {
+					return errors.Join(err, ErrRetryDeadline)
+				}
+				delay = 0
+				if policy.AttemptTimeout > 0 {
+					delay = max(left, 0)
+				}
+				attempts = attempt + 1
+			}
+		}
+		timer := clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C():
+		}
+	}
+}
+
+// RetryAttempt returns the attempt number, starting at 1, of the function Retry is calling with the given context. It
+// returns 0 if the context did not come from Retry.
+func RetryAttempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(uint)
+	return int(attempt)
+}
+
+// RetryBudget returns how long the attempt Retry is making with the given context should take, so the remaining
+// attempts and the waits between them fit before the context's deadline. It is never more than the policy's
+// AttemptTimeout. The function can use it to set timeouts of its own. ok is false if the context did not come from
+// Retry or there is no limit.
+func RetryBudget(ctx context.Context) (budget time.Duration, ok bool) {
+	budget, ok = ctx.Value(budgetKey{}).(time.Duration)
+	return budget, ok
+}
+
+// RetryOn returns a RetryIf function that retries only errors matching one of the targets with errors.Is.
+func RetryOn(targets ...error) func(err error) bool {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// try makes a single attempt for Retry. timedOut is true if the attempt ran out of its own AttemptTimeout while the
+// given context was still alive.
+func try(ctx context.Context, policy RetryPolicy, attempt uint, budget time.Duration, limited bool,
+	fn func(ctx context.Context) error) (timedOut bool, err error) {
+	attemptCtx := context.WithValue(ctx, attemptKey{}, attempt)
+	if limited {
+		attemptCtx = context.WithValue(attemptCtx, budgetKey{}, budget)
+	}
+	if policy.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(attemptCtx, policy.AttemptTimeout)
+		defer cancel()
+	}
+	err = fn(attemptCtx)
+	return attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil, err
+}
+
+// retryBudget is the token bucket shared by the work items of a Pool to limit retries. See WithRetryBudget.
+type retryBudget struct {
+	capacity  float64
+	mux       sync.Mutex
+	ratio     float64
+	throttled uint64
+	tokens    float64
+}
+
+// newRetryBudget creates a full retry budget with the given number of tokens that gives back the ratio for every
+// success. If the number of tokens is not positive, RetryBudgetTokens is used. It returns nil if the ratio is not
+// positive.
+func newRetryBudget(ratio, tokens float64) *retryBudget {
+	if ratio <= 0 {
+		return nil
+	}
+	if tokens <= 0 {
+		tokens = RetryBudgetTokens
+	}
+	return &retryBudget{
+		capacity: tokens,
+		ratio:    ratio,
+		tokens:   tokens,
+	}
+}
+
+// allow determines if a retry is allowed. If not, it is counted as throttled.
+func (b *retryBudget) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mux.Lock()
+	allowed := b.tokens > b.capacity/2
+	b.mux.Unlock()
+	if !allowed {
+		atomic.AddUint64(&b.throttled, 1)
+	}
+	return allowed
+}
+
+// level returns how many tokens are left.
+func (b *retryBudget) level() float64 {
+	if b == nil {
+		return 0
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.tokens
+}
+
+// record takes a token for a failure or gives back the ratio for a success.
+func (b *retryBudget) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if failed {
+		b.tokens = max(b.tokens-1, 0)
+		return
+	}
+	b.tokens = min(b.tokens+b.ratio, b.capacity)
+}
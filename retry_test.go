@@ -0,0 +1,368 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// TestRetry confirms that Retry waits between attempts and stops once the function succeeds.
+func TestRetry(t *testing.T) {
+
+	// Create a clock controlled by the test and a policy that backs off.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	policy := ctxerrpool.RetryPolicy{
+		Attempts: 5,
+		Backoff:  time.Second,
+		Clock:    clock,
+	}
+
+	// Fail the first two attempts.
+	var attempts []int
+	done := make(chan error)
+	go func() {
+		done <- ctxerrpool.Retry(context.Background(), policy, func(ctx context.Context) error {
+			attempts = append(attempts, ctxerrpool.RetryAttempt(ctx))
+			if len(attempts) < 3 {
+				return errors.New("test")
+			}
+			return nil
+		})
+	}()
+
+	// Let the backoff pass between attempts. The wait doubles each time.
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second * 2)
+
+	// Confirm the function succeeded on the third attempt.
+	if err := <-done; err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+		t.Errorf("Unexpected attempts: %v.", attempts)
+		t.FailNow()
+	}
+}
+
+// TestRetryAttemptTimeout confirms that each attempt is given its own timeout.
+func TestRetryAttemptTimeout(t *testing.T) {
+
+	// Wait for each attempt's context to time out.
+	calls := 0
+	policy := ctxerrpool.RetryPolicy{
+		Attempts:       2,
+		AttemptTimeout: time.Millisecond * 10,
+	}
+	err := ctxerrpool.Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// Confirm each attempt timed out and the next one was still made.
+	if calls != 2 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected 2 attempts that timed out. Attempts: %d. Error: %v", calls, err)
+		t.FailNow()
+	}
+}
+
+// TestRetryBudget confirms that each attempt is given its share of the time left before the deadline.
+func TestRetryBudget(t *testing.T) {
+
+	// Create a context with a deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Record the budget of each attempt and fail all but the last.
+	var budgets []time.Duration
+	policy := ctxerrpool.RetryPolicy{
+		Attempts:       2,
+		AttemptTimeout: 400 * time.Millisecond,
+	}
+	err := ctxerrpool.Retry(ctx, policy, func(ctx context.Context) error {
+		budget, ok := ctxerrpool.RetryBudget(ctx)
+		if !ok {
+			t.Errorf("The attempt had no budget.")
+		}
+		budgets = append(budgets, budget)
+		if len(budgets) < 2 {
+			return errors.New("test")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the first attempt got about half of the time and the attempt timeout capped the second.
+	if len(budgets) != 2 || budgets[0] > 500*time.Millisecond || budgets[0] < 400*time.Millisecond ||
+		budgets[1] != 400*time.Millisecond {
+		t.Errorf("Unexpected budgets: %v.", budgets)
+		t.FailNow()
+	}
+
+	// Confirm there is no budget outside of Retry.
+	if _, ok := ctxerrpool.RetryBudget(ctx); ok {
+		t.Errorf("A context that did not come from Retry had a budget.")
+		t.FailNow()
+	}
+}
+
+// TestRetryBudgetBackoffer confirms that the budget of each attempt leaves time for the waits of a custom Backoffer.
+func TestRetryBudgetBackoffer(t *testing.T) {
+
+	// Create a context with a deadline a second away on a clock controlled by the test. The deadline is a timer of its
+	// own.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	ctx, cancel := clock.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Use a custom Backoffer that always waits 300ms, record the budget of each attempt, and fail all but the last.
+	budgets := make(chan time.Duration, 2)
+	policy := ctxerrpool.RetryPolicy{
+		Attempts: 2,
+		Backoffer: backofferFunc(func(attempt int, prev time.Duration) time.Duration {
+			return 300 * time.Millisecond
+		}),
+		Clock: clock,
+	}
+	done := make(chan error)
+	go func() {
+		done <- ctxerrpool.Retry(ctx, policy, func(ctx context.Context) error {
+			budget, _ := ctxerrpool.RetryBudget(ctx)
+			budgets <- budget
+			if len(budgets) < 2 {
+				return errors.New("test")
+			}
+			return nil
+		})
+	}()
+	clock.BlockUntil(2)
+	clock.Advance(300 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm the first attempt shared the time left after the wait and the second got the rest.
+	if first, second := <-budgets, <-budgets; first != 350*time.Millisecond || second != 700*time.Millisecond {
+		t.Errorf("Unexpected budgets: %s, %s.", first, second)
+		t.FailNow()
+	}
+}
+
+// TestRetryContext confirms that Retry stops waiting between attempts when its context ends.
+func TestRetryContext(t *testing.T) {
+
+	// Create a context and a policy that waits longer than the test.
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	policy := ctxerrpool.RetryPolicy{
+		Attempts: 5,
+		Backoff:  time.Hour,
+		Clock:    clock,
+	}
+
+	// Always fail.
+	failure := errors.New("test")
+	done := make(chan error)
+	go func() {
+		done <- ctxerrpool.Retry(ctx, policy, func(ctx context.Context) error {
+			return failure
+		})
+	}()
+
+	// Cancel the context while Retry is waiting.
+	clock.BlockUntil(1)
+	cancel()
+
+	// Confirm both the last error and the context's error are returned.
+	if err := <-done; !errors.Is(err, failure) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestRetryDeadline confirms that Retry does not wait past the context's deadline when the policy says so.
+func TestRetryDeadline(t *testing.T) {
+
+	// Create a context with a deadline that is shorter than the backoff.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	failure := errors.New("test")
+
+	// Confirm GiveUpEarly returns after the first attempt.
+	calls := 0
+	policy := ctxerrpool.RetryPolicy{
+		Attempts:   5,
+		Backoff:    time.Hour,
+		OnDeadline: ctxerrpool.GiveUpEarly,
+	}
+	err := ctxerrpool.Retry(ctx, policy, func(ctx context.Context) error {
+		calls++
+		return failure
+	})
+	if calls != 1 || !errors.Is(err, failure) || !errors.Is(err, ctxerrpool.ErrRetryDeadline) ||
+		!errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to give up after 1 attempt. Attempts: %d. Error: %v", calls, err)
+		t.FailNow()
+	}
+
+	// Confirm FinalAttempt makes one more attempt right away.
+	calls = 0
+	policy.OnDeadline = ctxerrpool.FinalAttempt
+	err = ctxerrpool.Retry(ctx, policy, func(ctx context.Context) error {
+		calls++
+		return failure
+	})
+	if calls != 2 || !errors.Is(err, failure) || ctx.Err() != nil {
+		t.Errorf("Expected a final attempt before the deadline. Attempts: %d. Error: %v", calls, err)
+		t.FailNow()
+	}
+}
+
+// TestRetryExhausted confirms that Retry returns the last error once the attempts are used up.
+func TestRetryExhausted(t *testing.T) {
+
+	// Always fail without waiting between attempts.
+	calls := 0
+	failure := errors.New("test")
+	err := ctxerrpool.Retry(context.Background(), ctxerrpool.RetryPolicy{Attempts: 3}, func(ctx context.Context) error {
+		calls++
+		return failure
+	})
+
+	// Confirm every attempt was made.
+	if calls != 3 || !errors.Is(err, failure) {
+		t.Errorf("Expected 3 attempts and the last error. Attempts: %d. Error: %v", calls, err)
+		t.FailNow()
+	}
+}
+
+// TestRetryIf confirms that only errors the policy allows are retried and that context errors are not.
+func TestRetryIf(t *testing.T) {
+
+	// Create a retryable error and a policy that only retries it.
+	errTemporary := errors.New("temporary")
+	policy := ctxerrpool.RetryPolicy{
+		Attempts: 3,
+		RetryIf:  ctxerrpool.RetryOn(errTemporary),
+	}
+
+	// Confirm a matching error is retried.
+	calls := 0
+	err := ctxerrpool.Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("wrapped: %w", errTemporary)
+	})
+	if calls != 3 || !errors.Is(err, errTemporary) {
+		t.Errorf("Expected 3 attempts. Attempts: %d. Error: %v", calls, err)
+		t.FailNow()
+	}
+
+	// Confirm an error that does not match fails right away.
+	calls = 0
+	failure := errors.New("permanent")
+	err = ctxerrpool.Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return failure
+	})
+	if calls != 1 || !errors.Is(err, failure) {
+		t.Errorf("Expected 1 attempt. Attempts: %d. Error: %v", calls, err)
+		t.FailNow()
+	}
+
+	// Confirm context errors are not retried, even by a policy that retries every error.
+	policy = ctxerrpool.RetryPolicy{Attempts: 3}
+	for _, failure := range []error{context.Canceled, context.DeadlineExceeded} {
+		calls = 0
+		err = ctxerrpool.Retry(context.Background(), policy, func(ctx context.Context) error {
+			calls++
+			return failure
+		})
+		if calls != 1 || !errors.Is(err, failure) {
+			t.Errorf("Expected 1 attempt. Attempts: %d. Error: %v", calls, err)
+			t.FailNow()
+		}
+	}
+}
+
+// TestRetryJitter confirms the waits of each jitter strategy with a fixed random number and that Retry uses a custom
+// Backoffer.
+func TestRetryJitter(t *testing.T) {
+
+	// Create a policy whose random numbers are always one half.
+	policy := ctxerrpool.RetryPolicy{
+		Backoff: 100 * time.Millisecond,
+		Rand: func() float64 {
+			return 0.5
+		},
+	}
+
+	// Confirm the first two waits of each strategy.
+	expected := map[ctxerrpool.Jitter][2]time.Duration{
+		ctxerrpool.NoJitter:           {100 * time.Millisecond, 200 * time.Millisecond},
+		ctxerrpool.FullJitter:         {50 * time.Millisecond, 100 * time.Millisecond},
+		ctxerrpool.EqualJitter:        {75 * time.Millisecond, 150 * time.Millisecond},
+		ctxerrpool.DecorrelatedJitter: {200 * time.Millisecond, 350 * time.Millisecond},
+	}
+	for jitter, delays := range expected {
+		policy.Jitter = jitter
+		first := policy.NextDelay(1, 0)
+		second := policy.NextDelay(2, first)
+		if first != delays[0] || second != delays[1] {
+			t.Errorf("Unexpected waits for jitter %d: %s, %s.", jitter, first, second)
+			t.FailNow()
+		}
+	}
+
+	// Use a custom Backoffer that always waits a minute and records what it was given.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	var given []time.Duration
+	policy = ctxerrpool.RetryPolicy{
+		Attempts: 3,
+		Backoffer: backofferFunc(func(attempt int, prev time.Duration) time.Duration {
+			given = append(given, prev)
+			return time.Minute
+		}),
+		Clock: clock,
+	}
+	failure := errors.New("test")
+	done := make(chan error)
+	go func() {
+		done <- ctxerrpool.Retry(context.Background(), policy, func(ctx context.Context) error {
+			return failure
+		})
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	// Confirm the custom Backoffer was given the previous waits.
+	if err := <-done; !errors.Is(err, failure) {
+		t.Errorf("Unexpected error: %v", err)
+		t.FailNow()
+	}
+	if len(given) != 2 || given[0] != 0 || given[1] != time.Minute {
+		t.Errorf("Unexpected previous waits: %v.", given)
+		t.FailNow()
+	}
+}
+
+// backofferFunc is a function that implements ctxerrpool.Backoffer.
+type backofferFunc func(attempt int, prev time.Duration) time.Duration
+
+// NextDelay implements the ctxerrpool.Backoffer interface.
+func (f backofferFunc) NextDelay(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
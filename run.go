@@ -0,0 +1,72 @@
+package ctxerrpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// RunAll gives every Work function to the Pool under the given context, waits for all of them to finish, and returns
+// their errors. The error at index i is from works[i] and is nil on success. Each Work function receives its index as
+// its data. Work functions that could not run because the Pool is dead have an error matching ErrPoolKilled. Errors
+// are still reported to the error handler as usual.
+//
+// RunAll blocks until all the work is done, so calling it from within a Work function of the same Pool occupies a
+// worker while waiting. It panics with ErrNilWork without running anything if any of the Work functions is nil. With
+// WithReportNilWork, the nil Work functions are instead reported to the error handler and their error is ErrNilWork,
+// while the rest run.
+func (g *Pool) RunAll(ctx context.Context, works []Work) []error {
+	errs := make([]error, len(works))
+
+	// Refuse nil Work functions before accepting any of them.
+	for _, work := range works {
+		if work == nil && !g.cfg.reportNilWork {
+			panic(ErrNilWork)
+		}
+	}
+
+	// Give all the work to the pool and record each error as the work finishes.
+	wg := &sync.WaitGroup{}
+	wg.Add(len(works))
+	for i, work := range works {
+		if work == nil {
+			g.refuseNilWork()
+			errs[i] = ErrNilWork
+			wg.Done()
+			continue
+		}
+		i := i
+		g.addWorkItem(ctx, &workItem{
+			onFinish: func(err error) {
+				errs[i] = err
+				wg.Done()
+			},
+			work: work,
+			data: i,
+		})
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// RunN gives n copies of the Work function to the Pool under the given context, waits for all of them to finish, and
+// returns their errors joined with errors.Join, or nil if they all succeeded. Each copy receives its index, from 0 to
+// n-1, as its data, which is handy for splitting work into n shards. Each copy runs under its own context derived from
+// the given one, so ending one copy's context does not end the others. Errors are still reported to the error handler
+// as usual.
+//
+// Like RunAll, RunN blocks until all the work is done. It panics with ErrNilWork if the Work function is nil. With
+// WithReportNilWork, ErrNilWork is instead reported to the error handler and returned.
+func (g *Pool) RunN(ctx context.Context, n int, work Work) error {
+	if work == nil {
+		g.refuseNilWork()
+		return ErrNilWork
+	}
+	works := make([]Work, max(n, 0))
+	for i := range works {
+		works[i] = work
+	}
+	return errors.Join(g.RunAll(ctx, works)...)
+}
@@ -0,0 +1,93 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestRunAll confirms that RunAll returns the error of each Work function at its index.
+func TestRunAll(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {})
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Create work that fails at odd indexes.
+	works := make([]ctxerrpool.Work, 6)
+	for i := range works {
+		works[i] = func(workCtx context.Context, data interface{}) error {
+			if data.(int)%2 == 1 {
+				return io.EOF
+			}
+			return nil
+		}
+	}
+
+	// Confirm the errors line up with the work.
+	for i, err := range pool.RunAll(ctx, works) {
+		if i%2 == 1 && !errors.Is(err, io.EOF) || i%2 == 0 && err != nil {
+			t.Errorf("Unexpected error at index %d. Error: %v", i, err)
+			t.FailNow()
+		}
+	}
+
+	// Confirm work given to a dead pool has the right error.
+	pool.Kill()
+	for i, err := range pool.RunAll(ctx, works) {
+		if !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+			t.Errorf("Unexpected error at index %d. Error: %v", i, err)
+			t.FailNow()
+		}
+	}
+}
+
+// TestRunN confirms that RunN gives each copy its index and joins their errors.
+func TestRunN(t *testing.T) {
+
+	// Create a worker pool with 2 workers.
+	pool := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Create work that records its index and fails for index 3.
+	var seen [5]int64
+	work := func(workCtx context.Context, data interface{}) error {
+		atomic.AddInt64(&seen[data.(int)], 1)
+		if data.(int) == 3 {
+			return io.EOF
+		}
+		return nil
+	}
+
+	// Confirm every index ran once and the error was returned.
+	if err := pool.RunN(ctx, len(seen), work); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the error of the failing copy. Error: %v", err)
+		t.FailNow()
+	}
+	for i := range seen {
+		if count := atomic.LoadInt64(&seen[i]); count != 1 {
+			t.Errorf("Expected index %d to run once, but it ran %d times.", i, count)
+			t.FailNow()
+		}
+	}
+
+	// Confirm nil is returned when every copy succeeds.
+	if err := pool.RunN(ctx, 2, func(workCtx context.Context, data interface{}) error {
+		return nil
+	}); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,169 @@
+package ctxerrpool
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// cacheLine is the size of a CPU cache line. Counters updated by different workers are kept on separate cache lines so
+// the workers do not slow each other down.
+const cacheLine = 64
+
+// Stats is a snapshot of the counters kept by a Pool.
+type Stats struct {
+
+	// DroppedOldest is the number of queued work items that were dropped to make room for newer ones because of the
+	// DropOldest policy.
+	DroppedOldest uint64
+
+	// HandlerSaturated is the number of errors that had to wait for a running error handler to return because of
+	// WithMaxErrorConcurrency.
+	HandlerSaturated uint64
+
+	// Hedged is the number of copies of work items given to the Pool by SubmitHedged. The first attempt of each work
+	// item is not counted.
+	Hedged uint64
+
+	// Leaked is the number of Work functions that were still running after the watchdog's grace period. See
+	// WithWatchdog.
+	Leaked uint64
+
+	// MaxPending is the most work items that were ever queued at once, as counted by Pending, since the Pool was
+	// created or ResetStats was called. It tells how close the queue came to being full.
+	MaxPending int
+
+	// Overflowed is the number of work items that were put in the overflow buffer.
+	Overflowed uint64
+
+	// OverflowFull is the number of work items that found the queue and overflow buffer full, whatever the
+	// RejectionPolicy, so it tells how often the overflow buffer filled up. A work item that waits for room is only
+	// counted once. Rejected only counts the work items refused by the Reject policy.
+	OverflowFull uint64
+
+	// Queued is the number of work items in the queue. It is a gauge rather than a counter.
+	Queued int
+
+	// QueuedBytes is the total size of the work items that are queued or running, as given to AddWorkItemSized. It is
+	// a gauge rather than a counter. See WithMaxQueuedBytes.
+	QueuedBytes int64
+
+	// RetryBudget is how many tokens are left in the retry budget. It is a gauge rather than a counter. It is zero
+	// without WithRetryBudget.
+	RetryBudget float64
+
+	// RetryThrottled is the number of retries that were refused because the retry budget was used up.
+	RetryThrottled uint64
+
+	// Recycled is the number of workers that were replaced because of WithWorkerMaxItems or WithWorkerMaxAge.
+	Recycled uint64
+
+	// Rejected is the number of work items that were rejected because the Pool had no room for them under the Reject
+	// policy. See OverflowFull for every time the Pool had no room.
+	Rejected uint64
+
+	// Running is the number of work items that workers have started, but not finished. It is a gauge rather than a
+	// counter.
+	Running int
+
+	// Spilled is the number of re-entrant work items that were queued beyond the queue and overflow buffer. See
+	// WithSpillLimit.
+	Spilled uint64
+}
+
+// stats holds the counters for a Pool. All fields must be accessed atomically.
+type stats struct {
+	droppedOldest uint64
+	hedged        uint64
+	leaked        uint64
+	overflowed    uint64
+	recycled      uint64
+	rejected      uint64
+	saturated     uint64
+	spilled       uint64
+	submitted     uint64
+}
+
+// snapshot atomically reads the counters into a Stats.
+func (s *stats) snapshot() Stats {
+	return Stats{
+		DroppedOldest:    atomic.LoadUint64(&s.droppedOldest),
+		HandlerSaturated: atomic.LoadUint64(&s.saturated),
+		Hedged:           atomic.LoadUint64(&s.hedged),
+		Leaked:           atomic.LoadUint64(&s.leaked),
+		Overflowed:       atomic.LoadUint64(&s.overflowed),
+		Recycled:         atomic.LoadUint64(&s.recycled),
+		Rejected:         atomic.LoadUint64(&s.rejected),
+		Spilled:          atomic.LoadUint64(&s.spilled),
+	}
+}
+
+// WorkerStats is a snapshot of the counters kept for one worker of a Pool. A worker that replaced another one with
+// RecycleWorkers continues its counters.
+type WorkerStats struct {
+
+	// Busy is the total time the worker spent on work items.
+	Busy time.Duration
+
+	// Errored is the number of work items taken by the worker that failed.
+	Errored uint64
+
+	// LastActive is when the worker last finished a work item. It is the zero time if it never has.
+	LastActive time.Time
+
+	// Processed is the number of work items the worker finished.
+	Processed uint64
+
+	// Worker is the number of the worker, starting at 1.
+	Worker uint
+}
+
+// workerStats holds the counters for one worker. They are only written by the worker, which makes seq odd while it
+// does, so a snapshot can tell it must read them again. All fields must be accessed atomically. The padding is a whole
+// cache line, so the counters of neighboring workers in a slice are always at least a cache line apart, wherever the
+// slice happens to start.
+type workerStats struct {
+	busy       int64
+	errored    uint64
+	lastActive int64
+	processed  uint64
+	seq        uint64
+	_          [cacheLine]byte
+}
+
+// record counts a work item the worker spent the time between started and finished on.
+func (s *workerStats) record(started, finished time.Time, failed bool) {
+	atomic.AddUint64(&s.seq, 1)
+	atomic.AddInt64(&s.busy, int64(finished.Sub(started)))
+	if failed {
+		atomic.AddUint64(&s.errored, 1)
+	}
+	atomic.StoreInt64(&s.lastActive, finished.UnixNano())
+	atomic.AddUint64(&s.processed, 1)
+	atomic.AddUint64(&s.seq, 1)
+}
+
+// snapshot reads the counters into a WorkerStats. The counters are read again until the worker did not change them
+// while they were being read, so they are consistent with each other. While the worker is writing them, the processor
+// is yielded so the worker can finish.
+func (s *workerStats) snapshot(worker uint) (snapshot WorkerStats) {
+	for {
+		seq := atomic.LoadUint64(&s.seq)
+		if seq%2 == 1 {
+			runtime.Gosched()
+			continue
+		}
+		snapshot = WorkerStats{
+			Busy:      time.Duration(atomic.LoadInt64(&s.busy)),
+			Errored:   atomic.LoadUint64(&s.errored),
+			Processed: atomic.LoadUint64(&s.processed),
+			Worker:    worker,
+		}
+		if lastActive := atomic.LoadInt64(&s.lastActive); lastActive != 0 {
+			snapshot.LastActive = time.Unix(0, lastActive)
+		}
+		if atomic.LoadUint64(&s.seq) == seq {
+			return snapshot
+		}
+	}
+}
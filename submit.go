@@ -0,0 +1,251 @@
+package ctxerrpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// ErrInvalidSubmitOption indicates that the options given to Submit were invalid or could not be combined.
+var ErrInvalidSubmitOption = errors.New("invalid submit option")
+
+// SubmitOption changes how a single work item given to Submit is handled.
+type SubmitOption func(s *submission)
+
+// submission holds the settings for a work item given to Submit.
+type submission struct {
+	callback func(err error)
+	category string
+	data     interface{}
+	deadline time.Time
+	fields   map[string]string
+	handle   *Handle
+	handler  ErrorHandler
+	labels   map[string]string
+	lane     string
+	priority int
+	retry    *RetryPolicy
+	size     int64
+	timeout  time.Duration
+}
+
+// validate checks the settings for a work item given to Submit.
+func (s submission) validate() error {
+	if s.timeout < 0 {
+		return fmt.Errorf("%w: the timeout must not be negative", ErrInvalidSubmitOption)
+	}
+	if s.size < 0 {
+		return fmt.Errorf("%w: the size must not be negative", ErrInvalidSubmitOption)
+	}
+	if s.retry != nil && (s.retry.Backoff < 0 || s.retry.MaxBackoff < 0 || s.retry.Multiplier < 0) {
+		return fmt.Errorf("%w: the retry policy must not be negative", ErrInvalidSubmitOption)
+	}
+	return nil
+}
+
+// WithCallback sets a function that is called exactly once when the work item finishes, whether it succeeded, failed,
+// was rejected, or was dropped. The error that ended the work item is given, or nil if it succeeded.
+func WithCallback(callback func(err error)) SubmitOption {
+	return func(s *submission) {
+		s.callback = callback
+	}
+}
+
+// WithCategory puts the work item in the category, like AddWorkItemCategory.
+func WithCategory(category string) SubmitOption {
+	return func(s *submission) {
+		s.category = category
+	}
+}
+
+// WithData sets the data given to the Work function.
+func WithData(data interface{}) SubmitOption {
+	return func(s *submission) {
+		s.data = data
+	}
+}
+
+// WithDeadline ends the work item's context at the deadline, like AddWorkItemDeadline. It can only tighten the
+// context's own deadline. The zero time means there is no deadline.
+func WithDeadline(deadline time.Time) SubmitOption {
+	return func(s *submission) {
+		s.deadline = deadline
+	}
+}
+
+// WithErrorHandler gives errors for the work item to the handler instead of the Pool's, like AddWorkItemWithHandler.
+// A nil handler means the Pool's.
+func WithErrorHandler(handler ErrorHandler) SubmitOption {
+	return func(s *submission) {
+		s.handler = handler
+	}
+}
+
+// WithHandle makes the Handle follow the work item through its lifecycle. With SubmitHedged, the Handle follows the
+// first attempt.
+func WithHandle(handle *Handle) SubmitOption {
+	return func(s *submission) {
+		s.handle = handle
+	}
+}
+
+// WithLabels gives the work item labels, like AddWorkItemLabeled. The labels are copied.
+func WithLabels(labels map[string]string) SubmitOption {
+	return func(s *submission) {
+		s.labels = maps.Clone(labels)
+	}
+}
+
+// WithLane puts the work item in the serial lane, like AddSerial. An empty lane name means no lane.
+func WithLane(lane string) SubmitOption {
+	return func(s *submission) {
+		s.lane = lane
+	}
+}
+
+// WithLogFields gives the work item's context the logging fields, on top of the ones the context already carries. The
+// Work function and the work items it adds read them with LogFields. See ContextWithLogFields.
+func WithLogFields(fields map[string]string) SubmitOption {
+	return func(s *submission) {
+		s.fields = maps.Clone(fields)
+	}
+}
+
+// WithPriority sets the priority of the work item. Queued work items with a higher priority are taken by workers
+// first. Work items with the same priority are taken in the order they were queued. The default priority is zero.
+func WithPriority(priority int) SubmitOption {
+	return func(s *submission) {
+		s.priority = priority
+	}
+}
+
+// WithRetryPolicy makes the work item retry its Work function according to the policy, see Retry. If the policy has
+// no Clock, the Pool's Clock is used.
+func WithRetryPolicy(policy RetryPolicy) SubmitOption {
+	return func(s *submission) {
+		s.retry = &policy
+	}
+}
+
+// WithSize makes the work item as big as the given number of bytes, like AddWorkItemSized. It must not be negative.
+func WithSize(bytes int64) SubmitOption {
+	return func(s *submission) {
+		s.size = bytes
+	}
+}
+
+// WithTimeout limits how long the work item may take, starting when it is given to the Pool, so time spent waiting in
+// the queue counts. Zero means there is no limit.
+func WithTimeout(timeout time.Duration) SubmitOption {
+	return func(s *submission) {
+		s.timeout = timeout
+	}
+}
+
+// Submit gives the Work function to a worker like AddWorkItem, with the given options applied to the work item. If the
+// context is nil, the context is created by the Pool's context factory, which is set with WithContextFactory.
+//
+// ErrNilWork is returned if the Work function is nil. An error matching ErrInvalidSubmitOption is returned if the
+// options are invalid. The reason the Pool died is returned if it was dead. In all these cases, the work item was not
+// accepted and nothing is reported to the error handler. Otherwise, the work item's ID is returned and any errors are
+// reported to the error handler as usual.
+func (g *Pool) Submit(ctx context.Context, work Work, opts ...SubmitOption) (WorkID, error) {
+
+	// Refuse a nil Work function.
+	if work == nil {
+		return 0, ErrNilWork
+	}
+
+	// Apply and check the options.
+	s, err := newSubmission(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	// Give the work item to the Pool.
+	if id := g.addWorkItem(g.prepare(ctx, work, s)); id != 0 {
+		return id, nil
+	}
+
+	return 0, g.killCause()
+}
+
+// newSubmission applies the options to a submission and checks it.
+func newSubmission(opts []SubmitOption) (submission, error) {
+	s := submission{}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s, s.validate()
+}
+
+// prepare creates the context and work item for a Work function given to the Pool with the settings of the
+// submission.
+func (g *Pool) prepare(ctx context.Context, work Work, s submission) (context.Context, *workItem) {
+
+	// Create the context.
+	var release context.CancelFunc
+	if ctx == nil {
+		ctx, release = g.cfg.contextFactory()
+	}
+	if s.fields != nil {
+		ctx = ContextWithLogFields(ctx, s.fields)
+	}
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		release = chain(release, cancel)
+	}
+	if !s.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, s.deadline)
+		release = chain(release, cancel)
+	}
+
+	// Retry the Work function, if configured to.
+	if s.retry != nil {
+		policy := *s.retry
+		if policy.Clock == nil {
+			policy.Clock = g.cfg.clock
+		}
+		policy.throttle = g.budget
+		work = retried(work, policy)
+	}
+
+	return ctx, &workItem{
+		category:  s.category,
+		handle:    s.handle,
+		handler:   s.handler,
+		labels:    s.labels,
+		lane:      s.lane,
+		onFinish:  s.callback,
+		priority:  s.priority,
+		release:   release,
+		size:      s.size,
+		throttled: s.retry != nil,
+		work:      work,
+		data:      s.data,
+	}
+}
+
+// chain returns a function that calls both functions. Either may be nil.
+func chain(first, second context.CancelFunc) context.CancelFunc {
+	if first == nil {
+		return second
+	}
+	return func() {
+		second()
+		first()
+	}
+}
+
+// retried returns a Work function that calls the given one according to the retry policy.
+func retried(work Work, policy RetryPolicy) Work {
+	return func(workCtx context.Context, data interface{}) error {
+		return Retry(workCtx, policy, func(ctx context.Context) error {
+			return work(ctx, data)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestSubmit confirms that the options given to Submit are applied to the work item.
+func TestSubmit(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Submit a work item that fails once, has a timeout, and reports back when it finishes.
+	attempts := 0
+	finished := make(chan error, 1)
+	id, err := pool.Submit(ctx, func(workCtx context.Context, data interface{}) error {
+		attempts++
+		if data != "data" {
+			t.Errorf("Unexpected data: %v.", data)
+			t.FailNow()
+		}
+		if _, ok := workCtx.Deadline(); !ok {
+			t.Error("The work item did not have a timeout.")
+			t.FailNow()
+		}
+		if attempts == 1 {
+			return errors.New("test")
+		}
+		return nil
+	}, ctxerrpool.WithData("data"), ctxerrpool.WithTimeout(time.Second), ctxerrpool.WithCallback(func(err error) {
+		finished <- err
+	}), ctxerrpool.WithRetryPolicy(ctxerrpool.RetryPolicy{Attempts: 2}))
+	if err != nil || id != 1 {
+		t.Errorf("The work item was not accepted. ID: %d. Error: %v", id, err)
+		t.FailNow()
+	}
+
+	// Confirm the work item succeeded on its second attempt.
+	if err = <-finished; err != nil || attempts != 2 {
+		t.Errorf("Expected 2 attempts and no error. Attempts: %d. Error: %v", attempts, err)
+		t.FailNow()
+	}
+}
+
+// TestSubmitInvalid confirms that invalid options and dead pools are reported by Submit.
+func TestSubmitInvalid(t *testing.T) {
+
+	// Create a worker pool with 1 worker.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	})
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Confirm invalid options are rejected.
+	work := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	_, err := pool.Submit(ctx, work, ctxerrpool.WithTimeout(-time.Second))
+	if !errors.Is(err, ctxerrpool.ErrInvalidSubmitOption) {
+		t.Errorf("Expected an invalid option error. Error: %v", err)
+		t.FailNow()
+	}
+	if _, err = pool.Submit(ctx, work, ctxerrpool.WithSize(-1)); !errors.Is(err, ctxerrpool.ErrInvalidSubmitOption) {
+		t.Errorf("Expected an invalid option error. Error: %v", err)
+		t.FailNow()
+	}
+
+	// Confirm a dead pool is reported.
+	pool.Kill()
+	if _, err = pool.Submit(ctx, work); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected the pool to be dead. Error: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestSubmitOptions confirms that the options for the per-item features of the AddWorkItem methods are applied to the
+// work item.
+func TestSubmitOptions(t *testing.T) {
+
+	// Create a worker pool with 1 worker whose error handler should not be used.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("The pool's error handler was used. Error: %v", err)
+	})
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Submit a work item with every option and hold it while it runs.
+	errTest := errors.New("test")
+	deadline := time.Now().Add(time.Second / 2)
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	handled := make(chan error, 1)
+	_, err := pool.Submit(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return errTest
+	}, ctxerrpool.WithCategory("category"), ctxerrpool.WithDeadline(deadline), ctxerrpool.WithLane("lane"),
+		ctxerrpool.WithSize(10), ctxerrpool.WithErrorHandler(func(pool *ctxerrpool.Pool, err error) {
+			handled <- err
+		}))
+	if err != nil {
+		t.Errorf("The work item was not accepted. Error: %v", err)
+		t.FailNow()
+	}
+	<-started
+
+	// Confirm the work item has the category, deadline, lane, and size.
+	infos := pool.InFlightSnapshot()
+	if len(infos) != 1 || infos[0].Category != "category" || infos[0].Lane != "lane" ||
+		!infos[0].Deadline.Equal(deadline) {
+		t.Errorf("Unexpected running work items: %+v", infos)
+		t.FailNow()
+	}
+	if queued := pool.Stats().QueuedBytes; queued != 10 {
+		t.Errorf("Expected 10 bytes to be queued or running, but %d were.", queued)
+		t.FailNow()
+	}
+
+	// Confirm the error goes to the work item's error handler.
+	close(gate)
+	if err = <-handled; !errors.Is(err, errTest) {
+		t.Errorf("Unexpected error for the work item's error handler. Error: %v", err)
+		t.FailNow()
+	}
+	pool.Wait()
+}
+
+// TestWithPriority confirms that queued work items with a higher priority are taken first.
+func TestWithPriority(t *testing.T) {
+
+	// Create a worker pool with 1 worker and a queue.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithQueueSize(4))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep the worker busy until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, "busy")
+	<-started
+
+	// Queue work items with different priorities and record the order they run in.
+	mux := &sync.Mutex{}
+	var order []interface{}
+	work := func(workCtx context.Context, data interface{}) error {
+		mux.Lock()
+		defer mux.Unlock()
+		order = append(order, data)
+		return nil
+	}
+	for i, priority := range []int{0, 5, 0, 10} {
+		pool.Submit(ctx, work, ctxerrpool.WithData(i), ctxerrpool.WithPriority(priority))
+	}
+
+	// Let the work run and confirm the order.
+	close(gate)
+	pool.Wait()
+	mux.Lock()
+	defer mux.Unlock()
+	if len(order) != 4 || order[0] != 3 || order[1] != 1 || order[2] != 0 || order[3] != 2 {
+		t.Errorf("Unexpected order: %v.", order)
+		t.FailNow()
+	}
+}
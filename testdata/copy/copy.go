@@ -0,0 +1,16 @@
+// Package copy misuses a ctxerrpool.Pool by copying it. It is checked by go vet in TestNoCopy.
+package copy
+
+import (
+	"ctxerrpool"
+)
+
+// holder stores a Pool by value, which is a mistake.
+type holder struct {
+	pool ctxerrpool.Pool
+}
+
+// store copies the Pool into a holder.
+func store(pool *ctxerrpool.Pool) holder {
+	return holder{pool: *pool}
+}
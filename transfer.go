@@ -0,0 +1,47 @@
+package ctxerrpool
+
+// TransferPendingTo moves the queued work items that have not been taken by a worker yet to the other Pool and returns
+// how many were moved. Each work item is given to the other Pool under the context it was given to this Pool with,
+// along with its data, size, labels, priority, category, serial lane, error handler, callback, and retry and requeue
+// state, as if it had been given to the other Pool in the first place. Running work items, and those still waiting for
+// room in the queue, stay with this Pool.
+//
+// A moved work item stops counting towards this Pool's Wait only once the other Pool has accepted it, so Wait on
+// neither Pool returns while a work item is between them. Giving a work item to the other Pool blocks like AddWorkItem
+// when it has no room. If the other Pool is dead, the work items are dropped by it as usual and are not counted. Use it
+// to hand work over to a Pool with a different configuration without dropping it. Nothing is moved if the other Pool
+// is this Pool.
+func (g *Pool) TransferPendingTo(other *Pool) (moved int) {
+	if other == g {
+		return 0
+	}
+
+	for _, item := range g.queue.drain() {
+
+		// Give a copy of the work item to the other Pool. Its callback and context resources go with it.
+		id := other.addWorkItem(item.parent, &workItem{
+			category:  item.category,
+			handle:    item.handle,
+			handler:   item.handler,
+			labels:    item.labels,
+			lane:      item.lane,
+			onFinish:  item.onFinish,
+			priority:  item.priority,
+			release:   item.release,
+			requeues:  item.requeues,
+			size:      item.size,
+			throttled: item.throttled,
+			work:      item.work,
+			data:      item.data,
+		})
+		if id != 0 {
+			moved++
+		}
+
+		// Forget the work item here as if it was never given to this Pool.
+		item.release = nil
+		g.unaccept(item)
+	}
+
+	return moved
+}
@@ -0,0 +1,164 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+)
+
+// TestTransferPendingTo confirms that queued work items are moved to the other Pool and run there, while the running
+// work item stays with the original Pool.
+func TestTransferPendingTo(t *testing.T) {
+
+	// Create an error for the last work item.
+	errLast := errors.New("last")
+
+	// Create a worker pool with 1 worker and room for 3 queued work items.
+	from := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred in the original pool. Error: %v", err)
+	}, ctxerrpool.WithQueueSize(3))
+	defer from.Kill()
+
+	// Create a wait pool that waits for the error of the last work item to be handled.
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	// Create a worker pool with 2 workers to move the work items to.
+	to := ctxerrpool.New(2, func(pool *ctxerrpool.Pool, err error) {
+		defer wg.Done()
+
+		// This test case should only have the error of the last work item.
+		if !errors.Is(err, errLast) {
+			t.Errorf("An error occurred. Error: %v", err)
+			t.FailNow()
+		}
+	})
+	defer to.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep track of the work items that ran and a mutex for them.
+	mux := &sync.Mutex{}
+	var ran []int
+
+	// Keep the worker of the original pool busy until the gate is closed, then queue 3 work items behind it.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	from.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, 0)
+	<-started
+	for i := 1; i <= 3; i++ {
+		from.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			mux.Lock()
+			ran = append(ran, data.(int))
+			mux.Unlock()
+			if data == 3 {
+				return errLast
+			}
+			return nil
+		}, i)
+	}
+
+	// Move the queued work items and confirm they run in the other pool while the original one is still busy.
+	if moved := from.TransferPendingTo(to); moved != 3 {
+		t.Errorf("Expected 3 work items to be moved, but %d were.", moved)
+		t.FailNow()
+	}
+	if pending := from.PendingCount(); pending != 1 {
+		t.Errorf("Expected only the running work item to be left, but %d are.", pending)
+		t.FailNow()
+	}
+	to.Wait()
+	wg.Wait()
+	mux.Lock()
+	sort.Ints(ran)
+	if len(ran) != 3 || ran[0] != 1 || ran[1] != 2 || ran[2] != 3 {
+		t.Errorf("The moved work items did not run. Ran: %v", ran)
+		t.FailNow()
+	}
+	mux.Unlock()
+
+	// Let the running work item finish.
+	close(gate)
+	from.Wait()
+}
+
+// TestTransferPendingToSized confirms that a moved work item keeps its size, so it counts towards the other Pool's
+// WithMaxQueuedBytes limit and QueuedBytes.
+func TestTransferPendingToSized(t *testing.T) {
+
+	// Create a worker pool with 1 worker and room for 1 queued work item.
+	from := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred in the original pool. Error: %v", err)
+	}, ctxerrpool.WithQueueSize(1))
+	defer from.Kill()
+
+	// Create a worker pool with 1 worker that only allows 150 bytes to be queued or running.
+	to := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred in the other pool. Error: %v", err)
+	}, ctxerrpool.WithQueueSize(10), ctxerrpool.WithMaxQueuedBytes(150))
+	defer to.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Keep both workers busy until the gate is closed, holding 100 bytes in the other pool.
+	gate := make(chan struct{})
+	started := make(chan struct{}, 2)
+	work := func(workCtx context.Context, data interface{}) error {
+		started <- struct{}{}
+		<-gate
+		return nil
+	}
+	from.AddWorkItem(ctx, work, nil)
+	to.AddWorkItemSized(ctx, work, nil, 100)
+	<-started
+	<-started
+
+	// Queue a work item of 100 bytes in the original pool that runs until the second gate is closed.
+	moving := make(chan struct{})
+	last := make(chan struct{})
+	from.AddWorkItemSized(ctx, func(workCtx context.Context, data interface{}) error {
+		close(moving)
+		<-last
+		return nil
+	}, nil, 100)
+
+	// Confirm the moved work item waits for room under the other pool's limit.
+	moved := make(chan int)
+	go func() {
+		moved <- from.TransferPendingTo(to)
+	}()
+	select {
+	case n := <-moved:
+		t.Errorf("The work item was moved beyond the byte limit. Moved: %d", n)
+		t.FailNow()
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Let the running work items finish and confirm the work item was moved with its size.
+	close(gate)
+	if n := <-moved; n != 1 {
+		t.Errorf("Expected 1 work item to be moved, but %d were.", n)
+		t.FailNow()
+	}
+	<-moving
+	if bytes := to.Stats().QueuedBytes; bytes != 100 {
+		t.Errorf("Expected the moved work item to count 100 bytes, but %d were counted.", bytes)
+		t.FailNow()
+	}
+	close(last)
+	to.Wait()
+	from.Wait()
+}
@@ -2,8 +2,22 @@ package ctxerrpool
 
 import (
 	"context"
+	"errors"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
 )
 
+// noCopy may be embedded in a struct that must not be copied after first use. go vet's copylocks check reports copies
+// of a struct containing it.
+type noCopy struct{}
+
+// Lock is a no-op used by go vet's copylocks check.
+func (*noCopy) Lock() {}
+
+// Unlock is a no-op used by go vet's copylocks check.
+func (*noCopy) Unlock() {}
+
 // dead determines if the Pool is dead.
 func dead(death <-chan struct{}) bool {
 	select {
@@ -24,14 +38,125 @@ func expired(ctx context.Context) error {
 	}
 }
 
-// finished cancels the context and decrements the wait pool only once. It should be called when the worker is no
-// longer working on this workItem.
+// rejected determines if the error means a work item was refused before its Work function was called.
+func rejected(err error) bool {
+	return errors.Is(err, ErrQueueFull) || errors.Is(err, ErrCantDo) || errors.Is(err, ErrCircuitOpen)
+}
+
+// fail records the error that ended the work item. Only the first error is kept and errors after the work item has
+// finished are ignored.
+func (item *workItem) fail(err error) {
+	item.mux.Lock()
+	if item.err == nil && !item.decremented {
+		item.err = err
+	}
+	item.mux.Unlock()
+}
+
+// failed determines if an error has been recorded for the work item.
+func (item *workItem) failed() bool {
+	item.mux.Lock()
+	defer item.mux.Unlock()
+	return item.err != nil
+}
+
+// finished cancels the context, releases the context from the context factory, if any, calls the onFinish hooks, if
+// any, and decrements the wait pool only once. It should be called when the worker is no longer working on this
+// workItem.
 func (item *workItem) finished() {
 	item.mux.Lock()
-	if !item.decremented {
-		item.cancel()
-		item.decremented = true
-		item.wg.Done()
+	if item.decremented {
+		item.mux.Unlock()
+		return
 	}
+	item.decremented = true
+	err, ran := item.err, item.ran
 	item.mux.Unlock()
+
+	// Stamp the work item with the order it finished in.
+	g := item.pool
+	completeSeq := atomic.AddUint64(&g.cseq, 1)
+
+	item.cancel()
+	if item.release != nil {
+		item.release()
+	}
+	item.handle.finish(err, g.cfg.clock.Now())
+	event := Event{
+		Err:  err,
+		ID:   WorkID(item.seq),
+		Type: ItemFinished,
+	}
+	if !ran && rejected(err) {
+		event.Type = ItemRejected
+	}
+	g.emit(event)
+	if item.onFinish != nil {
+		item.onFinish(err)
+	}
+
+	// Measure how long the Work function ran and log the work item if it was slow.
+	var duration time.Duration
+	if !item.started.IsZero() {
+		duration = g.cfg.clock.Now().Sub(item.started)
+		if g.cfg.slowLog != nil && duration > g.cfg.slowThreshold {
+			g.cfg.slowLog(item.data, duration)
+		}
+	}
+
+	if g.cfg.onFinish != nil {
+		g.cfg.onFinish(FinishInfo{
+			CompleteSeq: completeSeq,
+			Data:        item.data,
+			Duration:    duration,
+			Err:         err,
+			Labels:      item.labels,
+			SubmitSeq:   item.seq,
+			Worker:      item.worker,
+		})
+	}
+	g.queue.forget(item)
+	if atomic.AddInt64(&g.pending, -1) == 0 {
+		g.idle()
+	}
+	g.progress()
+	g.wg.Done()
+}
+
+// labeled wraps the error in a WorkError if the work item has labels, so the labels reach the error handler.
+func (item *workItem) labeled(err error) error {
+	if item.labels == nil {
+		return err
+	}
+	return item.workError(err)
+}
+
+// report sends the error for the work item to the work item's error handler, if it has one, or to the Pool's.
+func (item *workItem) report(err error) {
+	g := item.pool
+	if item.handler == nil {
+		g.report(err)
+		return
+	}
+	g.spawn(func() {
+		g.handle(item.handler, err)
+	})
+}
+
+// spawn runs the function in a new goroutine that carries the Pool's profiler label. Goroutines started from it inherit
+// the label, so every goroutine the Pool owns can be found in a goroutine profile. See ProfileLabel.
+func (g *Pool) spawn(f func()) {
+	go func() {
+		pprof.SetGoroutineLabels(g.labels)
+		f()
+	}()
+}
+
+// workError wraps the error in a WorkError for the work item.
+func (item *workItem) workError(err error) *WorkError {
+	return &WorkError{
+		Data:   item.data,
+		Err:    err,
+		Labels: item.labels,
+	}
 }
@@ -0,0 +1,71 @@
+package ctxerrpool
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// MaxLeakedWork is the most work items LeakedWork keeps track of at once.
+const MaxLeakedWork = 100
+
+// leaks holds the IDs of work items that are suspected to have leaked their goroutine.
+type leaks struct {
+	ids map[WorkID]struct{}
+	mux sync.Mutex
+}
+
+// add starts tracking the work item as leaked, unless MaxLeakedWork are already tracked.
+func (l *leaks) add(id WorkID) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.ids == nil {
+		l.ids = make(map[WorkID]struct{})
+	}
+	if len(l.ids) < MaxLeakedWork {
+		l.ids[id] = struct{}{}
+	}
+}
+
+// remove stops tracking the work item as leaked.
+func (l *leaks) remove(id WorkID) {
+	l.mux.Lock()
+	delete(l.ids, id)
+	l.mux.Unlock()
+}
+
+// LeakedWork returns the IDs of the work items whose Work function is still running well after its worker stopped
+// waiting for it. At most MaxLeakedWork are tracked. It is always empty without WithWatchdog.
+func (g *Pool) LeakedWork() []WorkID {
+	g.leaks.mux.Lock()
+	defer g.leaks.mux.Unlock()
+	ids := make([]WorkID, 0, len(g.leaks.ids))
+	for id := range g.leaks.ids {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// watch waits for the grace period for the Work function of the work item to return. The given channel closes when
+// it returns. If it does not return in time, it is counted as leaked until it does.
+func (g *Pool) watch(item *workItem, finished <-chan struct{}) {
+
+	// Give the Work function the grace period to return.
+	timer := g.cfg.clock.NewTimer(g.cfg.watchdog)
+	select {
+	case <-finished:
+		timer.Stop()
+		return
+	case <-timer.C():
+	}
+
+	// The goroutine is suspected to have leaked until the Work function returns.
+	id := WorkID(item.seq)
+	atomic.AddUint64(&g.stats.leaked, 1)
+	g.leaks.add(id)
+	<-finished
+	g.leaks.remove(id)
+}
@@ -0,0 +1,76 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// TestWithWatchdog confirms that work which does not respect its context is counted as leaked after the grace period
+// and no longer reported once it returns.
+func TestWithWatchdog(t *testing.T) {
+
+	// Create a worker pool with 1 worker, a watchdog, and a clock controlled by the test.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {}, ctxerrpool.WithClock(clock),
+		ctxerrpool.WithWatchdog(time.Second))
+	defer pool.Kill()
+
+	// Create a context for the job that will time out.
+	ctx, cancel := clock.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	// Get a worker to ignore its context until the gate is closed.
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-gate
+		return nil
+	}, "test")
+	<-started
+
+	// Let the context time out, then let the grace period pass.
+	clock.Advance(time.Millisecond * 50)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	// Wait for the work item to be counted as leaked.
+	waitFor(t, func() bool {
+		leaked := pool.LeakedWork()
+		return pool.Stats().Leaked == 1 && len(leaked) == 1 && leaked[0] == 1
+	})
+
+	// Confirm the work item is no longer reported once it returns.
+	close(gate)
+	waitFor(t, func() bool {
+		return len(pool.LeakedWork()) == 0
+	})
+	if leaked := pool.Stats().Leaked; leaked != 1 {
+		t.Errorf("Expected 1 leaked work item to be counted, got %d.", leaked)
+		t.FailNow()
+	}
+}
+
+// waitFor polls the condition until it is true or fails the test after a second.
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Error("The condition was not met in time.")
+			t.FailNow()
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// settle waits for the Pool's work items to finish and then for its Work functions and error handlers to return, so
+// nothing is reported after it returns. It fails the test if they do not return within a second.
+func settle(t *testing.T, pool *ctxerrpool.Pool) {
+	t.Helper()
+	pool.Wait()
+	ctxerrpooltest.AssertNoLeaks(t, pool)
+}
@@ -3,77 +3,237 @@ package ctxerrpool
 import (
 	"context"
 	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
 
 	// ErrCantDo indicates that there was a failure to send the function to work on to a worker before the context
-	// expired.
+	// expired. The reported error is a CantDoError.
 	ErrCantDo = errors.New("failed to send work item to a worker before the context expired")
+
+	// ErrCircuitOpen indicates that the work item did not run because the Pool's circuit breaker was open.
+	ErrCircuitOpen = errors.New("work item did not run because the circuit breaker was open")
+
+	// ErrDroppedOldest indicates that the work item was removed from a full queue to make room for a newer one because
+	// of the DropOldest policy.
+	ErrDroppedOldest = errors.New("work item was dropped from a full queue to make room for a newer one")
+
+	// ErrMaxLifetime indicates that the Pool was killed because it reached the lifetime set with WithMaxLifetime. It
+	// matches ErrPoolKilled with errors.Is.
+	ErrMaxLifetime = fmt.Errorf("the pool reached its maximum lifetime: %w", ErrPoolKilled)
+
+	// ErrNilWork indicates that a nil Work function was given to the Pool. The Pool never accepts one. The methods
+	// that do not return an error panic with it at the call site instead, unless WithReportNilWork is set.
+	ErrNilWork = errors.New("nil Work function given to the pool")
+
+	// ErrPoolKilled indicates that the Pool was killed. It matches context.Canceled with errors.Is.
+	ErrPoolKilled = fmt.Errorf("the pool was killed: %w", context.Canceled)
+
+	// ErrPurged indicates that the work item was removed by PurgeQueue before a worker took it.
+	ErrPurged = errors.New("the work item was purged from the queue")
+
+	// ErrQueueFull indicates that the work item was rejected because all workers were busy and there was no room for
+	// it in the queue or overflow buffer.
+	ErrQueueFull = errors.New("failed to send work item to a worker because the queue was full")
+
+	// ErrSelfDeadlock indicates that every worker was waiting to add a work item to its own Pool, which would never
+	// have room for it. The work item was queued beyond the spill limit to break the deadlock. See
+	// WithDeadlockDetection.
+	ErrSelfDeadlock = errors.New("every worker was waiting to add a work item to its own pool")
+
+	// ErrWorkPanicked indicates that the work item's Work function panicked. The reported error is a PanicError.
+	ErrWorkPanicked = errors.New("work function panicked")
 )
 
-// Work is a function that utilizes the given context properly and returns an error.
+// Work is a function that utilizes the given context properly and returns an error. The context ends when the context
+// the work item was given to the Pool with ends or when the Pool dies, whichever comes first.
 type Work func(workCtx context.Context, data interface{}) (err error)
 
+// PoolWork is like Work, but it is also given the Pool that runs it. See Pool.AddPoolWorkItem.
+type PoolWork func(workCtx context.Context, pool *Pool, data interface{}) (err error)
+
 // workItem holds a function to work on and the context for it.
 type workItem struct {
+	abort       context.CancelCauseFunc
+	assigned    uint
 	cancel      context.CancelFunc
+	category    string
 	ctx         context.Context
 	decremented bool
+	err         error
+	full        bool
+	handle      *Handle
+	handler     ErrorHandler
+	labels      map[string]string
+	lane        string
 	mux         *sync.Mutex
-	wg          *sync.WaitGroup
+	onFinish    func(err error)
+	parent      context.Context
+	pool        *Pool
+	priority    int
+	purged      bool
+	quiet       bool
+	ran         bool
+	release     context.CancelFunc
+	requeue     *requeueError
+	reserved    bool
+	requeues    uint
+	seq         uint64
+	size        int64
+	started     time.Time
+	submitted   time.Time
+	submitter   *workItem
+	throttled   bool
+	weighed     bool
 	work        Work
+	worker      uint
 	data        interface{}
 }
 
-// worker consumes work items while from the Pool and sends unhandled errors back to the Pool error handler.
+// worker consumes work items from the Pool's queue and sends unhandled errors back to the Pool error handler. Its id
+// is the number of its slot in the Pool, so a replacement worker has the same id as the worker it replaced.
 type worker struct {
-	death   chan struct{}
-	do      <-chan *workItem
-	errChan chan<- error
+	dispatch *dispatcher
+	exited   chan struct{}
+	id       uint
+	initErr  error
+	initial  bool
+	pool     *Pool
+	ready    chan struct{}
+	retire   chan struct{}
 }
 
 // start is the main loop for a worker.
-func (w worker) start() {
+func (w *worker) start() {
+	defer close(w.exited)
+
+	// Get the worker ready, if configured to. A worker that fails to get ready never takes work items.
+	if w.pool.cfg.workerInit != nil {
+		w.initErr = w.pool.cfg.workerInit(w.id)
+	}
+	close(w.ready)
+	if w.initErr == nil {
+		w.pool.emit(Event{
+			Type:   WorkerStarted,
+			Worker: w.id,
+		})
+	}
+	if w.initial && atomic.AddInt64(&w.pool.starting, -1) == 0 {
+		close(w.pool.started)
+	}
+	if w.initErr != nil {
+		w.pool.report(&WorkerError{
+			Err:    w.initErr,
+			Worker: w.id,
+		})
+		return
+	}
+	if w.pool.cfg.workerTeardown != nil {
+		defer w.pool.cfg.workerTeardown(w.id)
+	}
+	defer w.pool.emit(Event{
+		Type:   WorkerStopped,
+		Worker: w.id,
+	})
 
-	// Wait for a condition in a loop until death.
+	// Take work items from the queue in a loop until death or retirement.
+	born := w.pool.cfg.clock.Now()
+	var processed uint64
 	for {
-		select {
 
-		// If told to die, end the goroutine.
-		case <-w.death:
+		// If told to die or retire, end the goroutine.
+		work, ok := w.pool.queue.pop(w.id, w.retire)
+		if !ok {
 			return
+		}
 
-		// If some work was received, do it.
-		case work := <-w.do:
-
-			// Consume the work item.
-			w.work(*work)
+		// Consume the work item.
+		started := w.pool.cfg.clock.Now()
+		w.work(work)
+		w.pool.queue.end(work)
+		w.pool.workerStats[w.id-1].record(started, w.pool.cfg.clock.Now(), work.failed())
 
-			// The work is finished.
+		// The work is finished, unless it asked to be requeued.
+		if !w.pool.requeue(work) {
 			work.finished()
+		}
 
-			// Prevent work from being randomly selected if both cases are ready.
-			if dead(w.death) {
-				return
-			}
+		// Prevent work from being taken if the pool died during the work.
+		if w.pool.Dead() {
+			return
+		}
+
+		// Retire if the worker has done enough.
+		processed++
+		if w.recycle(processed, born) {
+			return
 		}
 	}
 }
 
+// recycle replaces the worker with a new one if it has processed too many work items or lived too long. It returns true
+// if the worker was replaced and must return.
+func (w *worker) recycle(processed uint64, born time.Time) bool {
+	g := w.pool
+	age := g.cfg.clock.Now().Sub(born)
+	if (g.cfg.workerMaxItems == 0 || processed < g.cfg.workerMaxItems) &&
+		(g.cfg.workerMaxAge == 0 || age < g.cfg.workerMaxAge) {
+		return false
+	}
+
+	// Don't get in the way of RecycleWorkers or ReconfigureAndDrain.
+	if !g.recycle.TryLock() {
+		return false
+	}
+	defer g.recycle.Unlock()
+	if g.Dead() {
+		return false
+	}
+
+	// Start the replacement before retiring.
+	g.workerMux.Lock()
+	g.workers[w.id-1] = g.startWorker(w.id)
+	g.workerMux.Unlock()
+	atomic.AddUint64(&g.stats.recycled, 1)
+	if g.cfg.onRecycle != nil {
+		g.cfg.onRecycle(w.id, processed, age)
+	}
+
+	return true
+}
+
 // work is performed when a worker receives some work to do. If it returns true, the worker died before the work was
 // finished.
-func (w worker) work(item workItem) {
+func (w *worker) work(item *workItem) {
 
-	// Check to make sure the pool didn't die and work case was selected randomly.
-	if dead(w.death) {
+	// Check to make sure the pool didn't die after the work item was taken from the queue. This check is shared with
+	// Kill so no work starts after Kill returns.
+	begun, abort := w.pool.queue.begin(item, w.pool.cfg.clock.Now(), &w.pool.working)
+	if !begun {
+		w.pool.drop(item)
 		return
 	}
 
 	// Check to make sure the context is still valid.
 	if err := expired(item.ctx); err != nil {
-		w.errChan <- err
+		w.pool.working.Done()
+		item.fail(err)
+		item.report(item.labeled(err))
+		return
+	}
+
+	// Fail fast if the circuit breaker is open.
+	allowed, probe := w.pool.breaker.allow()
+	if !allowed {
+		w.pool.working.Done()
+		err := item.workError(ErrCircuitOpen)
+		item.fail(err)
+		item.report(err)
 		return
 	}
 
@@ -86,41 +246,109 @@ func (w worker) work(item workItem) {
 	// Create a channel that notifies us when the work has been completed.
 	finished := make(chan struct{})
 
-	// AddWorkItem the work asynchronously.
+	// AddWorkItem the work asynchronously. begin counted the goroutine, so KillAndWait can wait for it to return.
+	item.mux.Lock()
+	item.ran = true
+	item.mux.Unlock()
+	item.handle.enter(StateRunning, w.pool.cfg.clock.Now())
+
+	// KillWithPolicy cancels work items as they start if it canceled the running ones.
+	if abort {
+		item.fail(ErrPoolKilled)
+		item.abort(ErrPoolKilled)
+	}
+	w.pool.emit(Event{
+		ID:     WorkID(item.seq),
+		Type:   ItemStarted,
+		Worker: w.id,
+	})
 	go w.doWork(item, finished, hasCtxErr, muxCtxErr)
 
-	// Wait for a condition.
+	// Wait for a condition. With deterministic dispatch, the dispatcher picks between conditions that happened at once.
+	var condition int
 	select {
-
-	// The context is over. Report any error on the error channel.
 	case <-item.ctx.Done():
+		condition = 0
+	case <-w.pool.death:
+		condition = 1
+	case <-finished:
+		condition = 2
+	}
+	condition = w.dispatch.resolve(condition, item.ctx.Done(), w.pool.death, finished)
+
+	// The context also ends when the Pool dies, which is handled like death.
+	if condition == 0 && w.pool.ctx.Err() != nil {
+		condition = 1
+	}
+	switch condition {
+
+	// The context is over. Report any error on the error channel. A work item canceled by KillWithPolicy reports why.
+	case 0:
+		err := item.ctx.Err()
+		if cause := context.Cause(item.ctx); errors.Is(cause, ErrPoolKilled) {
+			err = cause
+		}
 		muxCtxErr.Lock()
 		if !*hasCtxErr {
 			*hasCtxErr = true
-			w.errChan <- item.ctx.Err()
+			item.fail(err)
+			item.report(item.labeled(err))
 		}
 		muxCtxErr.Unlock()
+		w.pool.breaker.record(true, probe)
 
-	// The worker died before finishing the work.
-	case <-w.death:
+	// The worker died before finishing the work. The context error the Work function returns is not reported.
+	case 1:
+		muxCtxErr.Lock()
+		*hasCtxErr = true
+		muxCtxErr.Unlock()
+		item.fail(w.pool.killCause())
 
-	// Successfully finished the work.
-	case <-finished:
+	// Finished the work.
+	case 2:
+		w.pool.breaker.record(item.failed(), probe)
+		return
+	}
+
+	// The Work function may not respect its context. Watch for it to leak, if configured to.
+	if w.pool.cfg.watchdog > 0 {
+		go w.pool.watch(item, finished)
 	}
 
 	return
 }
 
 // doWork actually performs the work item.
-func (w worker) doWork(item workItem, finished chan struct{}, hasCtxErr *bool, muxCtxErr *sync.Mutex) {
-	if err := item.work(item.ctx, item.data); err != nil {
+func (w *worker) doWork(item *workItem, finished chan struct{}, hasCtxErr *bool, muxCtxErr *sync.Mutex) {
+	defer w.pool.working.Done()
+
+	report, err := w.run(item)
+
+	// Spend or replenish the retry budget, unless the Work function retries and does so itself.
+	if !item.throttled {
+		w.pool.budget.record(err != nil)
+	}
+
+	// A Work function that ended because the Pool died is handled like death, so the context error is not reported.
+	if errors.Is(err, context.Canceled) && errors.Is(context.Cause(item.ctx), ErrPoolKilled) {
+		muxCtxErr.Lock()
+		*hasCtxErr = true
+		muxCtxErr.Unlock()
+		item.fail(w.pool.killCause())
+		err = nil
+	}
+
+	if err = item.requeued(err); err != nil {
 
 		// If the error is a context error and hasn't been reported already, report it. If it's not a context error,
 		// report it.
 		muxCtxErr.Lock()
 		if (!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)) || (errors.Is(err, context.Canceled) && !*hasCtxErr || errors.Is(err, context.DeadlineExceeded) && !*hasCtxErr) {
 			*hasCtxErr = true
-			w.errChan <- err
+			item.fail(err)
+			if report {
+				item.report(item.labeled(err))
+			}
 		}
 		muxCtxErr.Unlock()
 	}
@@ -128,3 +356,36 @@ func (w worker) doWork(item workItem, finished chan struct{}, hasCtxErr *bool, m
 	// The work is done.
 	close(finished)
 }
+
+// run calls the Work function. If it panics, the panic is handled according to the Pool's PanicPolicy and a PanicError
+// is returned. The returned bool determines if an error should be reported to the error handler.
+func (w *worker) run(item *workItem) (report bool, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		panicErr := &PanicError{
+			Data:      item.data,
+			Recovered: recovered,
+			Stack:     debug.Stack(),
+		}
+		cfg := &w.pool.cfg
+		switch {
+		case cfg.panicPolicy == Repanic:
+			go func() {
+				panic(recovered)
+			}()
+			report, err = false, panicErr
+		case cfg.panicPolicy == Handler && cfg.panicFunc != nil:
+			cfg.panicFunc(item.data, recovered, panicErr.Stack)
+			report, err = false, panicErr
+		case cfg.panicHandler != nil:
+			cfg.panicHandler(w.pool, recovered, panicErr.Stack)
+			report, err = false, panicErr
+		default:
+			report, err = true, panicErr
+		}
+	}()
+	return true, item.work(item.ctx, item.data)
+}
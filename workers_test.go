@@ -0,0 +1,265 @@
+package ctxerrpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ctxerrpool"
+	"ctxerrpool/ctxerrpooltest"
+)
+
+// TestRecycleWorkers confirms that every worker is replaced while work items keep flowing.
+func TestRecycleWorkers(t *testing.T) {
+
+	// Count the times each worker was set up and torn down.
+	mux := &sync.Mutex{}
+	inits := make(map[uint]int)
+	teardowns := make(map[uint]int)
+
+	// Create a worker pool with 3 workers and hooks.
+	pool := ctxerrpool.New(3, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+	}, ctxerrpool.WithWorkerInit(func(worker uint) error {
+		mux.Lock()
+		defer mux.Unlock()
+		inits[worker]++
+		return nil
+	}), ctxerrpool.WithWorkerTeardown(func(worker uint) {
+		mux.Lock()
+		defer mux.Unlock()
+		teardowns[worker]++
+	}))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// Keep a worker busy until told to finish, so the workers can't all be replaced until then.
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+		close(started)
+		<-finish
+		return nil
+	}, nil)
+	<-started
+
+	// Recycle the workers in another goroutine.
+	recycled := make(chan error, 1)
+	go func() {
+		recycled <- pool.RecycleWorkers(ctx)
+	}()
+
+	// Confirm work items keep flowing while the busy worker holds up the recycling.
+	for i := 0; i < 10; i++ {
+		done := make(chan struct{})
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			close(done)
+			return nil
+		}, nil)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("A work item was not completed while the workers were recycled.")
+			t.FailNow()
+		}
+	}
+	select {
+	case err := <-recycled:
+		t.Errorf("The workers were recycled before the busy worker finished. Error: %v", err)
+		t.FailNow()
+	default:
+	}
+
+	// Let the busy worker finish and wait for every worker to be replaced.
+	close(finish)
+	if err := <-recycled; err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	pool.Wait()
+
+	// Confirm every old worker was torn down and replaced.
+	mux.Lock()
+	for worker := uint(1); worker <= 3; worker++ {
+		if inits[worker] != 2 || teardowns[worker] != 1 {
+			t.Errorf("Worker %d was set up %d times and torn down %d times.", worker, inits[worker],
+				teardowns[worker])
+			t.FailNow()
+		}
+	}
+	mux.Unlock()
+
+	// Confirm the replacements are torn down when the pool dies.
+	if err := pool.KillAndWait(ctx); err != nil {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}
+	waitFor(t, func() bool {
+		mux.Lock()
+		defer mux.Unlock()
+		return teardowns[1] == 2 && teardowns[2] == 2 && teardowns[3] == 2
+	})
+}
+
+// TestWarmUp confirms that WarmUp waits for every worker to get ready and reports the workers that failed.
+func TestWarmUp(t *testing.T) {
+
+	// Create a worker pool with 3 workers whose init hooks wait for the gate. Worker 2 fails.
+	gate := make(chan struct{})
+	pool := ctxerrpool.New(3, func(pool *ctxerrpool.Pool, err error) {},
+		ctxerrpool.WithWorkerInit(func(worker uint) error {
+			<-gate
+			if worker == 2 {
+				return errors.New("test")
+			}
+			return nil
+		}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Warm up the pool in another goroutine.
+	warmed := make(chan error)
+	go func() {
+		warmed <- pool.WarmUp(ctx)
+	}()
+
+	// Confirm WarmUp waits for the init hooks.
+	select {
+	case err := <-warmed:
+		t.Errorf("WarmUp returned before the workers were ready. Error: %v", err)
+		t.FailNow()
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	// Let the workers get ready and confirm the failed worker is reported.
+	close(gate)
+	err := <-warmed
+	var workerErr *ctxerrpool.WorkerError
+	if !errors.As(err, &workerErr) || workerErr.Worker != 2 {
+		t.Errorf("Expected worker 2 to fail. Error: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestWarmUpKill confirms that killing the pool stops WarmUp from waiting.
+func TestWarmUpKill(t *testing.T) {
+
+	// Create a worker pool with 1 worker whose init hook does not return until the test ends.
+	gate := make(chan struct{})
+	defer close(gate)
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {},
+		ctxerrpool.WithWorkerInit(func(worker uint) error {
+			<-gate
+			return nil
+		}))
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Kill the pool while it warms up.
+	go pool.Kill()
+	if err := pool.WarmUp(ctx); !errors.Is(err, ctxerrpool.ErrPoolKilled) {
+		t.Errorf("Expected the pool to be killed. Error: %v", err)
+		t.FailNow()
+	}
+}
+
+// TestWithWorkerMaxAge confirms that a worker is replaced once it has lived too long.
+func TestWithWorkerMaxAge(t *testing.T) {
+
+	// Create a clock to control.
+	clock := ctxerrpooltest.NewClock(time.Time{})
+
+	// Record the age of the recycled workers and a mutex for them.
+	mux := &sync.Mutex{}
+	var ages []time.Duration
+
+	// Create a worker pool with 1 worker that may live for a minute.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithClock(clock), ctxerrpool.WithWorkerMaxAge(time.Minute),
+		ctxerrpool.WithOnWorkerRecycle(func(worker uint, processed uint64, age time.Duration) {
+			mux.Lock()
+			defer mux.Unlock()
+			ages = append(ages, age)
+		}))
+	defer pool.Kill()
+
+	// Give the pool work before and after the worker gets too old.
+	work := func(workCtx context.Context, data interface{}) error {
+		return nil
+	}
+	pool.AddWorkItem(context.Background(), work, nil)
+	pool.Wait()
+	if recycled := pool.Stats().Recycled; recycled != 0 {
+		t.Errorf("Expected a young worker to be kept, but %d were recycled.", recycled)
+		t.FailNow()
+	}
+	clock.Advance(2 * time.Minute)
+	pool.AddWorkItem(context.Background(), work, nil)
+	pool.Wait()
+
+	// Confirm the worker was replaced.
+	waitFor(t, func() bool {
+		return pool.Stats().Recycled == 1
+	})
+	mux.Lock()
+	defer mux.Unlock()
+	if len(ages) != 1 || ages[0] < 2*time.Minute {
+		t.Errorf("Unexpected ages of the recycled workers: %v.", ages)
+		t.FailNow()
+	}
+}
+
+// TestWithWorkerMaxItems confirms that a worker is replaced each time it has processed enough work items and that its
+// replacement is set up.
+func TestWithWorkerMaxItems(t *testing.T) {
+
+	// Count the times the worker was set up and torn down.
+	var inits, teardowns int32
+
+	// Create a worker pool with 1 worker that may process 2 work items.
+	pool := ctxerrpool.New(1, func(pool *ctxerrpool.Pool, err error) {
+		t.Errorf("An error occurred. Error: %v", err)
+		t.FailNow()
+	}, ctxerrpool.WithWorkerMaxItems(2), ctxerrpool.WithWorkerInit(func(worker uint) error {
+		atomic.AddInt32(&inits, 1)
+		return nil
+	}), ctxerrpool.WithWorkerTeardown(func(worker uint) {
+		atomic.AddInt32(&teardowns, 1)
+	}))
+	defer pool.Kill()
+
+	// Create a context.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Give the pool 5 work items.
+	var completed int32
+	for i := 0; i < 5; i++ {
+		pool.AddWorkItem(ctx, func(workCtx context.Context, data interface{}) error {
+			atomic.AddInt32(&completed, 1)
+			return nil
+		}, i)
+	}
+	pool.Wait()
+
+	// Confirm the worker was replaced after the second and fourth work items.
+	waitFor(t, func() bool {
+		return pool.Stats().Recycled == 2 && atomic.LoadInt32(&inits) == 3 && atomic.LoadInt32(&teardowns) == 2
+	})
+	if completed := atomic.LoadInt32(&completed); completed != 5 {
+		t.Errorf("Expected 5 completed work items, but got %d.", completed)
+		t.FailNow()
+	}
+}